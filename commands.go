@@ -0,0 +1,157 @@
+package main
+
+import (
+	"backend/config"
+	"backend/repository"
+	"backend/services"
+	"context"
+	"flag"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"time"
+)
+
+// runCommand runs a maintenance command instead of the server and returns the process exit code.
+//
+// Supported commands:
+//   - migrate indexes [-drop-undeclared]: makes the indexes of the post collections match repository.PostIndexes,
+//     recreating the indexes that drifted and, with -drop-undeclared, dropping the indexes that are not declared
+//   - migrate keys [-dry-run]: rewrites the posts stored before posts were keyed by models.PostKey, merging their
+//     duplicates; the server also runs it at startup, so it is mostly useful to preview the migration
+//   - migrate history [-dry-run]: moves the vote histories kept on the posts by earlier versions into the vote
+//     snapshots; the server also runs it at startup, after the key migration
+//   - maintenance [-dry-run]: applies the retention policy once, like the scheduled maintenance job
+func runCommand(db *mongo.Database, args []string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	defer config.DisconnectMongoClient(ctx)
+
+	if len(args) >= 2 && args[0] == "migrate" {
+		switch args[1] {
+		case "indexes":
+			return migrateIndexes(ctx, db, args[2:])
+		case "keys":
+			return migrateKeys(ctx, db, args[2:])
+		case "history":
+			return migrateHistory(ctx, db, args[2:])
+		}
+	}
+	if len(args) >= 1 && args[0] == "maintenance" {
+		return runMaintenance(ctx, db, args[1:])
+	}
+	log.Printf("Unknown command %q (want \"migrate indexes\", \"migrate keys\", \"migrate history\" or \"maintenance\")", args)
+	return 2
+}
+
+// migrateIndexes applies the declared indexes to every post collection and logs the drift it repaired.
+func migrateIndexes(ctx context.Context, db *mongo.Database, args []string) int {
+	flags := flag.NewFlagSet("migrate indexes", flag.ContinueOnError)
+	dropUndeclared := flags.Bool("drop-undeclared", false, "drop the indexes that are not declared")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	status := 0
+	for _, posts := range postCollections {
+		name := posts.Name
+		drifts, err := repository.MigrateIndexes(ctx, db.Collection(name), repository.PostIndexes, *dropUndeclared)
+		for _, drift := range drifts {
+			log.Printf("Index %s of %s was %s: %s", drift.Index, name, drift.Problem, drift.Detail)
+		}
+		if err != nil {
+			log.Printf("Failed to migrate indexes of %s: %v", name, err)
+			status = 1
+			continue
+		}
+		log.Printf("Indexes of %s are up to date", name)
+	}
+	return status
+}
+
+// migrateKeys rewrites the legacy posts of every post collection under their key and logs what it merged.
+func migrateKeys(ctx context.Context, db *mongo.Database, args []string) int {
+	flags := flag.NewFlagSet("migrate keys", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the posts to migrate without writing")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	status := 0
+	for _, posts := range postCollections {
+		result, err := repository.MigratePostKeys(ctx, db.Collection(posts.Name), posts.Source, *dryRun)
+		if result != nil {
+			for _, postErr := range result.Errors {
+				log.Printf("Failed to migrate a post of %s: %v", posts.Name, postErr)
+			}
+			log.Printf("Migrated %s: %d legacy documents into %d posts, %d duplicates merged, %d failed (dry run %t)",
+				posts.Name, result.Documents, result.Posts, result.Merged, result.Failed, *dryRun)
+			if result.Failed > 0 {
+				status = 1
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to migrate keys of %s: %v", posts.Name, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// migrateHistory moves the legacy vote histories of every post collection into the vote snapshots and logs how many.
+func migrateHistory(ctx context.Context, db *mongo.Database, args []string) int {
+	flags := flag.NewFlagSet("migrate history", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the histories to migrate without writing")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	snapshots, err := snapshotRepository(ctx, db)
+	if err != nil {
+		log.Printf("Failed to set up vote snapshots: %v", err)
+		return 1
+	}
+	status := 0
+	for _, posts := range postCollections {
+		result, err := repository.MigrateVoteHistory(ctx, db.Collection(posts.Name), snapshots, *dryRun)
+		if result != nil {
+			for _, postErr := range result.Errors {
+				log.Printf("Failed to migrate a vote history of %s: %v", posts.Name, postErr)
+			}
+			log.Printf("Migrated the vote histories of %d posts of %s into %d snapshots, %d failed (dry run %t)",
+				result.Posts, posts.Name, result.Snapshots, result.Failed, *dryRun)
+			if result.Failed > 0 {
+				status = 1
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to migrate vote histories of %s: %v", posts.Name, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// runMaintenance applies the retention policy to the vote snapshots and the post collections; the report is logged.
+func runMaintenance(ctx context.Context, db *mongo.Database, args []string) int {
+	flags := flag.NewFlagSet("maintenance", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be compacted and deleted without writing")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	policy := services.RetentionPolicyFromEnv()
+	if err := policy.Validate(); err != nil {
+		log.Printf("Invalid retention policy: %v", err)
+		return 2
+	}
+	snapshots, err := snapshotRepository(ctx, db)
+	if err != nil {
+		log.Printf("Failed to set up vote snapshots: %v", err)
+		return 1
+	}
+	if _, err := services.RunMaintenance(ctx, snapshots, maintenanceStores(db), policy, time.Now(), *dryRun); err != nil {
+		log.Printf("Failed to run maintenance: %v", err)
+		return 1
+	}
+	return 0
+}
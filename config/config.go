@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // GetEnv retrieves the value of the environment variable identified by the key.
@@ -29,3 +32,103 @@ func GetEnv(key string, defaultValue string) string {
 	// Return the value of the environment variable
 	return value
 }
+
+// GetEnvInt retrieves the value of the environment variable identified by the key as an integer.
+// If the environment variable is not set or is not a valid integer, it logs a message and returns the provided defaultValue.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The value to return if the environment variable is not set or invalid.
+//
+// Returns:
+//   - An int containing the parsed value of the environment variable, or defaultValue.
+func GetEnvInt(key string, defaultValue int) int {
+	// Retrieve the raw value, falling back to the string form of the default
+	raw := GetEnv(key, strconv.Itoa(defaultValue))
+
+	// Parse the value as an integer
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		// Log the invalid value and fall back to the default
+		log.Printf("Invalid integer value for %s: %s, using default %d", key, raw, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetEnvDuration retrieves the value of the environment variable identified by the key as a Go duration (e.g., "30s").
+// If the environment variable is not set or is not a valid positive duration, it logs a message and returns the provided defaultValue.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The value to return if the environment variable is not set or invalid.
+//
+// Returns:
+//   - A time.Duration containing the parsed value of the environment variable, or defaultValue.
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	// Retrieve the raw value, falling back to the string form of the default
+	raw := GetEnv(key, defaultValue.String())
+
+	// Parse the value as a duration
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		// Log the invalid value and fall back to the default
+		log.Printf("Invalid duration value for %s: %s, using default %s", key, raw, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetEnvBool retrieves the value of the environment variable identified by the key as a boolean ("true", "1", "false", "0", ...).
+// If the environment variable is not set or is not a valid boolean, it logs a message and returns the provided defaultValue.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The value to return if the environment variable is not set or invalid.
+//
+// Returns:
+//   - A bool containing the parsed value of the environment variable, or defaultValue.
+func GetEnvBool(key string, defaultValue bool) bool {
+	// Retrieve the raw value, falling back to the string form of the default
+	raw := GetEnv(key, strconv.FormatBool(defaultValue))
+
+	// Parse the value as a boolean
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		// Log the invalid value and fall back to the default
+		log.Printf("Invalid boolean value for %s: %s, using default %t", key, raw, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetEnvList retrieves the value of the environment variable identified by the key as a comma-separated list.
+// Surrounding whitespace and empty entries are dropped. If the environment variable is not set or contains no entries,
+// the provided defaultValue is returned.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The list to return if the environment variable is not set or empty.
+//
+// Returns:
+//   - A slice of strings containing the entries of the environment variable, or defaultValue.
+func GetEnvList(key string, defaultValue []string) []string {
+	// Retrieve the raw value, falling back to the joined default
+	raw := GetEnv(key, strings.Join(defaultValue, ","))
+
+	// Split the value and drop empty entries
+	var values []string
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			values = append(values, entry)
+		}
+	}
+
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
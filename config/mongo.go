@@ -54,12 +54,13 @@ func InitializeMongoClient() *mongo.Client {
 
 // DisconnectMongoClient disconnects the MongoDB client from the server if it is connected.
 //
-// It logs an error if the disconnection fails and prints a message upon successful disconnection.
-func DisconnectMongoClient() {
+// It waits for in-progress operations until ctx is done, logs an error if the disconnection fails
+// and prints a message upon successful disconnection.
+func DisconnectMongoClient(ctx context.Context) {
 	// Check if the MongoClient is initialized
 	if MongoClient != nil {
 		// Attempt to disconnect the MongoDB client
-		err := MongoClient.Disconnect(context.TODO())
+		err := MongoClient.Disconnect(ctx)
 		if err != nil {
 			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
 		}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// subredditPattern matches a subreddit name, or several names joined with "+" for a multireddit.
+var subredditPattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,21}(\+[A-Za-z0-9_]{2,21})*$`)
+
+// GetRedditSubreddits retrieves the watchlist of subreddits to track from the REDDIT_SUBREDDITS environment variable.
+//
+// The variable holds a comma-separated list of subreddit names, with or without the "r/" prefix.
+// Multireddits can be tracked by joining names with "+" (e.g., "golang+rust").
+// If the variable is not set, the watchlist defaults to "all".
+//
+// Returns:
+//   - A slice of normalized subreddit names without the "r/" prefix, or an error if a name is invalid.
+func GetRedditSubreddits() ([]string, error) {
+	var subreddits []string
+	for _, subreddit := range GetEnvList("REDDIT_SUBREDDITS", []string{"all"}) {
+		normalized, err := NormalizeSubreddit(subreddit)
+		if err != nil {
+			return nil, err
+		}
+		subreddits = append(subreddits, normalized)
+	}
+	return subreddits, nil
+}
+
+// NormalizeSubreddit strips the optional "/r/" or "r/" prefix and any trailing slash from a subreddit name,
+// and checks that the rest is a valid subreddit or multireddit name. Since the name becomes part of the path of
+// authenticated API requests, anything else (slashes, dots, query strings) is rejected.
+//
+// Parameters:
+//   - subreddit: The subreddit name as written by the user (e.g., "r/golang", "/r/golang+rust/").
+//
+// Returns:
+//   - The bare subreddit name (e.g., "golang", "golang+rust"), or an error if the name is invalid.
+func NormalizeSubreddit(subreddit string) (string, error) {
+	subreddit = strings.Trim(strings.TrimSpace(subreddit), "/")
+	subreddit = strings.TrimPrefix(subreddit, "r/")
+	if !subredditPattern.MatchString(subreddit) {
+		return "", fmt.Errorf("invalid subreddit name %q", subreddit)
+	}
+	return subreddit, nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// JobSchedule describes when the job of a trend source runs.
+//
+// Exactly one of Interval and Cron must be set. Durations are written as Go duration strings (e.g., "90s", "1h").
+type JobSchedule struct {
+	Name     string `json:"name"`     // Name of the job, defaults to the source name
+	Source   string `json:"source"`   // Name of the source the schedule applies to (e.g., reddit:golang, hackernews:top)
+	Interval string `json:"interval"` // Fixed interval between runs (e.g., "1m")
+	Cron     string `json:"cron"`     // Standard five-field cron expression (e.g., "0 * * * *")
+	Timezone string `json:"timezone"` // IANA timezone the cron expression is evaluated in, defaults to UTC
+	Jitter   string `json:"jitter"`   // Maximum random delay added before every run, defaults to none
+	Enabled  *bool  `json:"enabled"`  // Whether the job is scheduled, defaults to true
+}
+
+// ScheduleConfig holds the schedules of all jobs.
+// Sources without a schedule of their own use the default schedule.
+type ScheduleConfig struct {
+	Default JobSchedule   `json:"default"` // Schedule of the sources not listed in Jobs
+	Jobs    []JobSchedule `json:"jobs"`    // Schedules of individual sources
+}
+
+// DefaultScheduleConfig returns the schedule used when no configuration file exists:
+// every source runs every 5 minutes in UTC.
+func DefaultScheduleConfig() *ScheduleConfig {
+	return &ScheduleConfig{
+		Default: JobSchedule{Interval: "5m"},
+	}
+}
+
+// LoadScheduleConfig reads the schedule configuration from the JSON file at path.
+//
+// If the file does not exist, it logs a message and returns DefaultScheduleConfig.
+// Every schedule is validated, so a broken configuration is reported at startup rather than when a job runs.
+// Two schedules for the same source, or two jobs with the same name, are rejected, since only one of them
+// could ever be used. The sources without a schedule of their own run as jobs named after the source, so an
+// explicit job name that equals one of them is rejected as well.
+//
+// Parameters:
+//   - path: The path of the JSON configuration file.
+//   - sources: The names of the sources and tasks that will be scheduled.
+//
+// Returns:
+//   - A pointer to the loaded ScheduleConfig, or an error if the file cannot be read or is invalid.
+func LoadScheduleConfig(path string, sources []string) (*ScheduleConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("Schedule configuration %s not found, running every source every 5 minutes", path)
+		return DefaultScheduleConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule configuration: %v", err)
+	}
+
+	var cfg ScheduleConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule configuration: %v", err)
+	}
+
+	// Fall back to the built-in default when the file only lists individual jobs
+	if cfg.Default.Interval == "" && cfg.Default.Cron == "" {
+		cfg.Default.Interval = DefaultScheduleConfig().Default.Interval
+	}
+
+	if err := cfg.Default.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid default schedule: %v", err)
+	}
+	scheduled := make(map[string]bool, len(cfg.Jobs))
+	names := make(map[string]bool, len(cfg.Jobs))
+	for _, job := range cfg.Jobs {
+		if job.Source == "" {
+			return nil, fmt.Errorf("schedule %q has no source", job.Name)
+		}
+		if err := job.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid schedule for %s: %v", job.Source, err)
+		}
+
+		// Jobs are named after their source unless named explicitly
+		name := job.Name
+		if name == "" {
+			name = job.Source
+		}
+		if scheduled[job.Source] {
+			return nil, fmt.Errorf("duplicate schedule for source %s", job.Source)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate job name %s", name)
+		}
+		scheduled[job.Source] = true
+		names[name] = true
+	}
+
+	// The jobs of the sources using the default schedule are named after their source
+	for _, source := range sources {
+		if !scheduled[source] && names[source] {
+			return nil, fmt.Errorf("duplicate job name %s: the source of that name runs under it with the default schedule", source)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// For returns the schedule of the given source, or the default schedule named after the source
+// if the source has no schedule of its own.
+func (c *ScheduleConfig) For(source string) JobSchedule {
+	for _, job := range c.Jobs {
+		if job.Source == source {
+			if job.Name == "" {
+				job.Name = source
+			}
+			return job
+		}
+	}
+
+	schedule := c.Default
+	schedule.Name = source
+	schedule.Source = source
+	return schedule
+}
+
+// Lookup returns the schedule of the given source and true, or false if the source has no schedule of its own.
+// Unlike For, it does not fall back to the default schedule, for jobs whose default differs from the sources'.
+func (c *ScheduleConfig) Lookup(source string) (JobSchedule, bool) {
+	for _, job := range c.Jobs {
+		if job.Source == source {
+			return c.For(source), true
+		}
+	}
+	return JobSchedule{}, false
+}
+
+// Validate checks that exactly one of Interval and Cron is set and that the durations and timezone can be parsed.
+// Cron expressions themselves are validated by the scheduler when the job is created.
+func (s JobSchedule) Validate() error {
+	if (s.Interval == "") == (s.Cron == "") {
+		return fmt.Errorf("exactly one of interval and cron must be set")
+	}
+	if s.Interval != "" {
+		interval, err := time.ParseDuration(s.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval: %v", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	}
+	if _, err := s.JitterDuration(); err != nil {
+		return err
+	}
+	if _, err := s.Location(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsEnabled reports whether the job is scheduled. Schedules are enabled unless explicitly disabled.
+func (s JobSchedule) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// IntervalDuration returns the parsed interval, or zero for cron schedules.
+func (s JobSchedule) IntervalDuration() time.Duration {
+	interval, _ := time.ParseDuration(s.Interval)
+	return interval
+}
+
+// JitterDuration returns the parsed jitter, or zero if none is configured.
+func (s JobSchedule) JitterDuration() (time.Duration, error) {
+	if s.Jitter == "" {
+		return 0, nil
+	}
+	jitter, err := time.ParseDuration(s.Jitter)
+	if err != nil || jitter < 0 {
+		return 0, fmt.Errorf("invalid jitter %q", s.Jitter)
+	}
+	return jitter, nil
+}
+
+// Location returns the timezone of the schedule, UTC if none is configured.
+func (s JobSchedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", s.Timezone, err)
+	}
+	return location, nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadScheduleConfigRejectsImplicitNameClash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	data := `{"jobs": [{"name": "reddit:golang", "source": "reddit:rust", "interval": "1m"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// reddit:golang has no schedule of its own, so its default job is named after it
+	if _, err := LoadScheduleConfig(path, []string{"reddit:golang", "reddit:rust"}); err == nil {
+		t.Fatal("loaded a job named like the default job of another source")
+	}
+
+	// Without that source the name is free
+	if _, err := LoadScheduleConfig(path, []string{"reddit:rust"}); err != nil {
+		t.Fatal(err)
+	}
+}
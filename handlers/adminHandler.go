@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"backend/scheduler"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// RequireAdminToken wraps a handler so that it only serves requests carrying the admin token
+// in an "Authorization: Bearer <token>" header.
+// If no admin token is configured, every request is rejected so the admin API is disabled by default.
+func RequireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		// Compare in constant time so the token cannot be guessed from response times
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// TriggerJobHandler starts a run of the job identified by the "name" path variable immediately.
+// It responds with 202 Accepted and the recorded run, whose ID can be polled at /jobs/{name}/runs/{id},
+// with 409 Conflict if a run of the job is already in progress, or with 503 Service Unavailable if this replica
+// is not the leader and the request should be retried against another one.
+func TriggerJobHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	name := mux.Vars(r)["name"]
+
+	run, err := sched.Trigger(r.Context(), name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		http.Error(w, "Job is already running", http.StatusConflict)
+		return
+	case errors.Is(err, scheduler.ErrNotLeader):
+		http.Error(w, "Jobs run on another replica", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, scheduler.ErrStopped):
+		http.Error(w, "Scheduler is shutting down", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		log.Printf("Failed to trigger job %s: %v", name, err)
+		http.Error(w, "Failed to trigger job", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Triggered job %s, run %s", name, run.ID.Hex())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	// Encode the response with the started run
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Message: "Job run started", Data: run})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// PauseJobHandler stops the scheduled runs of the job identified by the "name" path variable on every replica.
+func PauseJobHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	setJobPaused(w, r, sched, true)
+}
+
+// ResumeJobHandler restarts the scheduled runs of the job identified by the "name" path variable.
+func ResumeJobHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	setJobPaused(w, r, sched, false)
+}
+
+// setJobPaused pauses or resumes a job and responds with a confirmation message, or with 503 Service Unavailable
+// if this replica is not the leader and the request should be retried against another one.
+func setJobPaused(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler, paused bool) {
+	name := mux.Vars(r)["name"]
+
+	var err error
+	message := "Job paused"
+	if paused {
+		err = sched.Pause(r.Context(), name)
+	} else {
+		err = sched.Resume(r.Context(), name)
+		message = "Job resumed"
+	}
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrNotLeader):
+		http.Error(w, "Jobs run on another replica", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		log.Printf("Failed to change state of job %s: %v", name, err)
+		http.Error(w, "Failed to change job state", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the confirmation message
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Message: message})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
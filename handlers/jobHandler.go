@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"backend/scheduler"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// JobsHandler lists the scheduler jobs with their schedule, next run, last success and last failure.
+// It responds with 503 Service Unavailable if this replica is not the leader and the request should be retried
+// against another one.
+func JobsHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	// Retrieve the status of every job
+	jobs, err := sched.Jobs(r.Context())
+	if errors.Is(err, scheduler.ErrNotLeader) {
+		http.Error(w, "Jobs run on another replica", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to retrieve job statuses: %v", err)
+		http.Error(w, "Failed to retrieve job statuses", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the job statuses
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: jobs})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// JobRunsHandler lists the most recent runs of the job identified by the "name" path variable, newest first.
+// The number of runs can be set with the "limit" query parameter and defaults to 20.
+func JobRunsHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	name := mux.Vars(r)["name"]
+	if sched.Job(name) == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	// Parse the limit parameter
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20 // Default limit if parsing fails or limit is invalid
+	}
+
+	// Retrieve the runs of the job from the database
+	runs, err := sched.Runs(r.Context(), name, int64(limit))
+	if err != nil {
+		log.Printf("Failed to retrieve runs of job %s: %v", name, err)
+		http.Error(w, "Failed to retrieve job runs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the job runs
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: runs})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// JobRunHandler returns a single run of a job, identified by the "name" and "id" path variables.
+// It lets callers poll the outcome of a run started through the admin API.
+func JobRunHandler(w http.ResponseWriter, r *http.Request, sched *scheduler.Scheduler) {
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve the run from the database
+	run, err := sched.Run(r.Context(), vars["name"], id)
+	if err != nil {
+		log.Printf("Failed to retrieve run %s of job %s: %v", vars["id"], vars["name"], err)
+		http.Error(w, "Failed to retrieve job run", http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Job run not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the job run
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: run})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
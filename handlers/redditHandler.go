@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"backend/models"
+	"backend/config"
+	"backend/repository"
 	"backend/services"
-	"context"
 	"encoding/json"
-	"go.mongodb.org/mongo-driver/bson"
+	"fmt"
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 )
 
@@ -20,23 +21,57 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"` // Optional data payload
 }
 
-// TrendingHandler handles the request for fetching trending posts from Reddit.
-func TrendingHandler(w http.ResponseWriter, r *http.Request) {
+// TrendingHandler handles the request for fetching trending posts from Reddit through the given client.
+// The subreddit can be selected with the "subreddit" query parameter and defaults to r/all.
+// The listing can be selected with the "sort" and "t" query parameters and defaults to the listing of opts.
+func TrendingHandler(w http.ResponseWriter, r *http.Request, client *services.RedditClient, opts services.ListingOptions) {
 	w.Header().Set("Content-Type", "application/json")
 
+	// Retrieve the subreddit from the query parameters
+	subreddit := r.URL.Query().Get("subreddit")
+	if subreddit == "" {
+		subreddit = "all" // Default to r/all if no subreddit is given
+	}
+	subreddit, err := config.NormalizeSubreddit(subreddit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Override the configured listing with the sort and time window from the query parameters
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		parsed, err := services.ParseListingSort(sort)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Sort = parsed
+	}
+	if window := r.URL.Query().Get("t"); window != "" {
+		parsed, err := services.ParseTimeWindow(window)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Window = parsed
+	}
+
 	// Fetch trending posts from the Reddit service
-	posts, err := services.FetchRedditTrendingPosts()
+	result, err := client.FetchTrendingPosts(r.Context(), subreddit, opts)
 	if err != nil {
 		log.Printf("Failed to fetch trending posts: %v", err)
 		http.Error(w, "Failed to fetch trending posts", http.StatusInternalServerError)
 		return
 	}
+	if result.Skipped > 0 {
+		log.Printf("Skipped %d malformed trending posts", result.Skipped)
+	}
 
 	// Construct a successful response
 	response := Response{
 		Status:  "success",
 		Message: "Trending posts fetched successfully",
-		Data:    posts,
+		Data:    result.Posts,
 	}
 
 	// Encode the response to JSON and send it back to the client
@@ -47,10 +82,22 @@ func TrendingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// FetchTrendingInDB retrieves trending posts from the MongoDB collection.
-func FetchTrendingInDB(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+// RateLimitHandler reports the current Reddit rate-limit budget of the client as tracked from the API response headers.
+func RateLimitHandler(w http.ResponseWriter, r *http.Request, client *services.RedditClient) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Encode the budget snapshot and send it back to the client
+	err := json.NewEncoder(w).Encode(Response{Status: "success", Data: client.RateLimit()})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// FetchTrendingInDB retrieves trending posts from the post repository.
+func FetchTrendingInDB(w http.ResponseWriter, r *http.Request, posts repository.PostRepository) {
 	// Retrieve trending posts from the database
-	posts, err := services.RetrieveRedditData(collection)
+	stored, err := services.RetrieveRedditData(r.Context(), posts)
 	if err != nil {
 		log.Printf("Failed to retrieve trending posts from DB: %v", err)
 		http.Error(w, "Failed to retrieve trending posts from DB", http.StatusInternalServerError)
@@ -59,7 +106,7 @@ func FetchTrendingInDB(w http.ResponseWriter, r *http.Request, collection *mongo
 
 	w.Header().Set("Content-Type", "application/json")
 	// Encode the response with the retrieved posts
-	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: posts})
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: stored})
 	if err != nil {
 		log.Printf("Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -67,62 +114,177 @@ func FetchTrendingInDB(w http.ResponseWriter, r *http.Request, collection *mongo
 }
 
 // FetchFilteredPostsHandler handles requests to fetch posts based on filters like sentiment, pagination, etc.
-func FetchFilteredPostsHandler(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
-	// Retrieve the sentiment filter from the query parameters
-	sentiment := r.URL.Query().Get("sentiment")
+//
+// Besides "limit" and "page", the following query parameters are supported:
+//   - sentiment, subreddit, author, flair, domain, source: exact matches on the corresponding post field
+//   - nsfw, spoiler, stickied, is_self: boolean flags ("true" or "false")
+//   - min_score, min_comments: lower bounds on the score and the number of comments
+//   - sort: rank, score, upvotes, comments, created or inserted; "order" is "asc" (default) or "desc"
+func FetchFilteredPostsHandler(w http.ResponseWriter, r *http.Request, posts repository.PostRepository) {
+	// Construct the query from the query parameters
+	query, err := buildPostQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Query the repository for posts
+	found, err := posts.Find(r.Context(), query)
+	if err != nil {
+		log.Printf("Failed to find posts: %v", err)
+		http.Error(w, "Failed to find posts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode and send the retrieved posts as a JSON response
+	if err := json.NewEncoder(w).Encode(found); err != nil {
+		log.Printf("Error encoding posts to JSON: %v", err)
+		http.Error(w, "Error encoding posts to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// PostStatsHandler groups the stored posts by the field given in the "group_by" query parameter
+// (source, sentiment, subreddit, community, domain or author, default sentiment) and returns the size,
+// average score and sentiment breakdown of every group. It accepts the filters of FetchFilteredPostsHandler.
+func PostStatsHandler(w http.ResponseWriter, r *http.Request, posts repository.PostRepository) {
+	filter, err := buildPostFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy := repository.GroupSentiment
+	if value := r.URL.Query().Get("group_by"); value != "" {
+		if groupBy, err = repository.ParseGroupField(value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Aggregate the matching posts
+	groups, err := posts.Aggregate(r.Context(), filter, groupBy)
+	if err != nil {
+		log.Printf("Failed to aggregate posts: %v", err)
+		http.Error(w, "Failed to aggregate posts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the groups
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: groups})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// FetchPostCommentsHandler retrieves the stored comments of the post identified by the "id" path variable.
+func FetchPostCommentsHandler(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	postID := mux.Vars(r)["id"]
+
+	// Retrieve the comments of the post from the database
+	comments, err := services.RetrieveRedditComments(r.Context(), collection, postID)
+	if err != nil {
+		log.Printf("Failed to retrieve comments of post %s from DB: %v", postID, err)
+		http.Error(w, "Failed to retrieve comments from DB", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the retrieved comments
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: comments})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// buildPostQuery constructs the repository query for FetchFilteredPostsHandler from the query parameters.
+// It returns an error describing the first invalid parameter.
+func buildPostQuery(values url.Values) (repository.PostQuery, error) {
+	filter, err := buildPostFilter(values)
+	if err != nil {
+		return repository.PostQuery{}, err
+	}
+	query := repository.PostQuery{Filter: filter}
+
+	// Parse the sort field and order
+	if value := values.Get("sort"); value != "" {
+		if query.Sort, err = repository.ParseSortField(value); err != nil {
+			return repository.PostQuery{}, err
+		}
+	}
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return repository.PostQuery{}, fmt.Errorf("invalid value for order: %q", order)
+	}
 
 	// Parse the limit parameter for pagination
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit, err := strconv.Atoi(values.Get("limit"))
 	if err != nil || limit <= 0 {
 		limit = 10 // Default limit if parsing fails or limit is invalid
 	}
 
 	// Parse the page parameter for pagination
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	page, err := strconv.Atoi(values.Get("page"))
 	if err != nil || page <= 0 {
 		page = 1 // Default page if parsing fails or page is invalid
 	}
 
-	// Construct the filter based on sentiment
-	filter := bson.M{}
-	if sentiment != "" {
-		filter["sentiment"] = sentiment
-	}
-
-	// Calculate the number of documents to skip for pagination
-	skip := int64((page - 1) * limit)
+	// Calculate the number of posts to skip for pagination
+	query.Limit = limit
+	query.Skip = (page - 1) * limit
+	return query, nil
+}
 
-	// Set up find options for limit and skip
-	findOptions := options.Find().SetLimit(int64(limit)).SetSkip(skip)
+// buildPostFilter constructs the repository filter from the query parameters.
+// It returns an error describing the first invalid parameter.
+func buildPostFilter(values url.Values) (repository.PostFilter, error) {
+	filter := repository.PostFilter{
+		Source:    values.Get("source"),
+		Sentiment: values.Get("sentiment"),
+		Subreddit: values.Get("subreddit"),
+		Author:    values.Get("author"),
+		Flair:     values.Get("flair"),
+		Domain:    values.Get("domain"),
+	}
 
-	// Query the MongoDB collection for posts
-	cursor, err := collection.Find(r.Context(), filter, findOptions)
-	if err != nil {
-		log.Printf("Failed to find posts: %v", err)
-		http.Error(w, "Failed to find posts", http.StatusInternalServerError)
-		return
+	// Boolean flags, keyed by query parameter
+	boolFields := map[string]**bool{
+		"nsfw":     &filter.Over18,
+		"spoiler":  &filter.Spoiler,
+		"stickied": &filter.Stickied,
+		"is_self":  &filter.IsSelf,
 	}
-	defer func(cursor *mongo.Cursor, ctx context.Context) {
-		// Close the cursor after the function completes
-		err := cursor.Close(ctx)
-		if err != nil {
-			log.Printf("Failed to close cursor: %v", err)
+	for param, field := range boolFields {
+		if value := values.Get(param); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return repository.PostFilter{}, fmt.Errorf("invalid value for %s: %q", param, value)
+			}
+			*field = &parsed
 		}
-	}(cursor, r.Context())
-
-	// Decode the retrieved posts into a slice
-	var posts []models.RedditPost
-	if err := cursor.All(context.Background(), &posts); err != nil {
-		log.Printf("Failed to decode posts: %v", err)
-		http.Error(w, "Failed to decode posts", http.StatusInternalServerError)
-		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	// Encode and send the retrieved posts as a JSON response
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		log.Printf("Error encoding posts to JSON: %v", err)
-		http.Error(w, "Error encoding posts to JSON", http.StatusInternalServerError)
-		return
+	// Lower bounds on numeric fields, keyed by query parameter
+	minFields := map[string]**int{
+		"min_score":    &filter.MinScore,
+		"min_comments": &filter.MinComments,
 	}
+	for param, field := range minFields {
+		if value := values.Get(param); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return repository.PostFilter{}, fmt.Errorf("invalid value for %s: %q", param, value)
+			}
+			*field = &parsed
+		}
+	}
+
+	return filter, nil
 }
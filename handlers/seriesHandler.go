@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"backend/repository"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultSeriesWindow is the time range of a series when the "from" query parameter is omitted.
+const defaultSeriesWindow = 7 * 24 * time.Hour
+
+// maxSeriesPoints bounds the number of intervals a series at a coarser resolution than raw may span.
+const maxSeriesPoints = 10000
+
+// PostSeriesHandler returns the vote series of the post whose key is the "key" path variable (e.g., t3_abc123).
+//
+// The following query parameters are supported:
+//   - resolution: "raw" (default) for every snapshot, or an interval such as "5m", "1h" or "24h"
+//   - from, to: time range in RFC 3339 format; "to" defaults to now and "from" to seven days before "to"
+func PostSeriesHandler(w http.ResponseWriter, r *http.Request, snapshots repository.SnapshotRepository) {
+	key := mux.Vars(r)["key"]
+
+	query, err := buildSeriesQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Summarize the snapshots of the post
+	points, err := snapshots.Series(r.Context(), key, query)
+	if err != nil {
+		log.Printf("Failed to retrieve vote series of post %s: %v", key, err)
+		http.Error(w, "Failed to retrieve vote series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Encode the response with the series
+	err = json.NewEncoder(w).Encode(Response{Status: "success", Data: points})
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// buildSeriesQuery constructs the repository query for PostSeriesHandler from the query parameters.
+// It returns an error describing the first invalid parameter.
+func buildSeriesQuery(values url.Values, now time.Time) (repository.SeriesQuery, error) {
+	resolution, err := repository.ParseResolution(values.Get("resolution"))
+	if err != nil {
+		return repository.SeriesQuery{}, err
+	}
+
+	// Parse the time range
+	query := repository.SeriesQuery{Resolution: resolution, To: now}
+	if value := values.Get("to"); value != "" {
+		if query.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return repository.SeriesQuery{}, fmt.Errorf("invalid to %q (want RFC 3339)", value)
+		}
+	}
+	query.From = query.To.Add(-defaultSeriesWindow)
+	if value := values.Get("from"); value != "" {
+		if query.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return repository.SeriesQuery{}, fmt.Errorf("invalid from %q (want RFC 3339)", value)
+		}
+	}
+	if !query.From.Before(query.To) {
+		return repository.SeriesQuery{}, fmt.Errorf("from must be before to")
+	}
+
+	if resolution > 0 && query.To.Sub(query.From)/resolution > maxSeriesPoints {
+		return repository.SeriesQuery{}, fmt.Errorf("resolution %s is too fine for the range (at most %d points)", resolution, maxSeriesPoints)
+	}
+	return query, nil
+}
@@ -3,12 +3,24 @@ package main
 import (
 	"backend/config"
 	"backend/handlers"
+	"backend/models"
+	"backend/repository"
 	"backend/scheduler"
+	"backend/services"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
+	"go.mongodb.org/mongo-driver/mongo"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Embed the timezone database for schedules with a timezone in minimal containers
 )
 
 func init() {
@@ -19,27 +31,330 @@ func init() {
 }
 func main() {
 	client := config.InitializeMongoClient()
-	collection := client.Database("trendlens").Collection("reddit_posts")
+	db := client.Database("trendlens")
 
-	scheduler.StartRedditScheduler(collection)
+	// Commands such as "migrate indexes" run instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(db, os.Args[1:]))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	snapshots, err := snapshotRepository(ctx, db)
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to set up vote snapshots: %v", err)
+	}
+
+	// Migrate the posts stored before posts were keyed by models.PostKey and their legacy vote histories, then
+	// create the declared indexes of the post collections and check the existing ones for drift
+	policy, err := repository.ParseDriftPolicy(config.GetEnv("INDEX_DRIFT", string(repository.DriftLog)))
+	if err != nil {
+		log.Fatalf("Failed to configure index management: %v", err)
+	}
+	for _, posts := range postCollections {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		err := migrateCollection(ctx, db.Collection(posts.Name), posts.Source, snapshots, policy)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to migrate %s: %v", posts.Name, err)
+		}
+	}
+
+	posts := repository.NewMongoPostRepository(db.Collection("reddit_posts"))
+	commentsCollection := db.Collection("reddit_comments")
+
+	redditClient, err := services.NewRedditClientFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure Reddit client: %v", err)
+	}
+	// The configured listing is read once and shared by the Reddit targets and the /trending endpoint
+	listingOptions := services.DefaultListingOptions()
+
+	targets := buildTargets(db, snapshots, redditClient, listingOptions)
+	tasks := []scheduler.Task{buildMaintenanceTask(db, snapshots)}
+	schedules, err := config.LoadScheduleConfig(config.GetEnv("SCHEDULE_CONFIG", "schedules.json"), scheduler.JobSources(targets, tasks))
+	if err != nil {
+		log.Fatalf("Failed to load schedule configuration: %v", err)
+	}
+
+	// Elect a single replica to run the scheduled jobs; every replica keeps serving HTTP
+	lease := scheduler.NewLease(
+		db.Collection("scheduler_leases"),
+		"scheduler",
+		config.GetEnv("SCHEDULER_OWNER_ID", scheduler.DefaultOwnerID()),
+		config.GetEnvDuration("SCHEDULER_LEASE_TTL", 30*time.Second),
+	)
+	lease.Start()
+
+	jobRunsCollection := db.Collection("job_runs")
+	sched, err := scheduler.StartScheduler(targets, tasks, schedules, jobRunsCollection, db.Collection("job_states"), lease)
+	if err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/trending", handlers.TrendingHandler).Methods("GET")
+	router.HandleFunc("/trending", func(w http.ResponseWriter, r *http.Request) {
+		handlers.TrendingHandler(w, r, redditClient, listingOptions)
+	}).Methods("GET")
+	router.HandleFunc("/ratelimit", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RateLimitHandler(w, r, redditClient)
+	}).Methods("GET")
 	router.HandleFunc("/stored_posts", func(w http.ResponseWriter, r *http.Request) {
-		handlers.FetchTrendingInDB(w, r, collection)
+		handlers.FetchTrendingInDB(w, r, posts)
 	}).Methods("GET")
 	router.HandleFunc("/filtered_posts", func(w http.ResponseWriter, r *http.Request) {
-		handlers.FetchFilteredPostsHandler(w, r, collection)
+		handlers.FetchFilteredPostsHandler(w, r, posts)
+	}).Methods("GET")
+	router.HandleFunc("/posts/stats", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PostStatsHandler(w, r, posts)
+	}).Methods("GET")
+	router.HandleFunc("/posts/{key}/series", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PostSeriesHandler(w, r, snapshots)
+	}).Methods("GET")
+	router.HandleFunc("/posts/{id}/comments", func(w http.ResponseWriter, r *http.Request) {
+		handlers.FetchPostCommentsHandler(w, r, commentsCollection)
+	}).Methods("GET")
+	router.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		handlers.JobsHandler(w, r, sched)
+	}).Methods("GET")
+	router.HandleFunc("/jobs/{name}/runs", func(w http.ResponseWriter, r *http.Request) {
+		handlers.JobRunsHandler(w, r, sched)
+	}).Methods("GET")
+	router.HandleFunc("/jobs/{name}/runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlers.JobRunHandler(w, r, sched)
 	}).Methods("GET")
 
+	// Admin endpoints controlling the scheduler, authenticated with ADMIN_TOKEN
+	adminToken := config.GetEnv("ADMIN_TOKEN", "")
+	router.HandleFunc("/admin/jobs/{name}/run", handlers.RequireAdminToken(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		handlers.TriggerJobHandler(w, r, sched)
+	})).Methods("POST")
+	router.HandleFunc("/admin/jobs/{name}/pause", handlers.RequireAdminToken(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		handlers.PauseJobHandler(w, r, sched)
+	})).Methods("POST")
+	router.HandleFunc("/admin/jobs/{name}/resume", handlers.RequireAdminToken(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		handlers.ResumeJobHandler(w, r, sched)
+	})).Methods("POST")
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET"},
-		AllowedHeaders:   []string{"Content-Type"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
 		AllowCredentials: true,
 	})
 	handler := c.Handler(router)
 
-	log.Println("Server is running on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	server := &http.Server{Addr: ":8080", Handler: handler}
+
+	// Serve until SIGINT or SIGTERM is received
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Println("Server is running on port 8080")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	stop() // A second signal kills the process immediately
+
+	shutdown(server, sched, lease, config.GetEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+}
+
+// shutdown stops the backend in dependency order so that nothing is written to MongoDB after it is disconnected:
+// it stops accepting requests and drains the in-flight handlers, stops the scheduler and waits for the running jobs,
+// releases the scheduler lease so another replica can take over immediately, and finally disconnects from MongoDB.
+// The HTTP server and the scheduler share the timeout; jobs still running when it expires are cancelled and
+// waited for briefly. The lease and the MongoDB client get a fresh timeout, since the shared one may have expired.
+func shutdown(server *http.Server, sched *scheduler.Scheduler, lease *scheduler.Lease, timeout time.Duration) {
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to drain HTTP server: %v", err)
+	}
+	if err := sched.Stop(ctx); err != nil {
+		log.Printf("Failed to stop scheduler gracefully: %v", err)
+	}
+	lease.Release()
+
+	disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer disconnectCancel()
+	config.DisconnectMongoClient(disconnectCtx)
+}
+
+// migrateCollection migrates the legacy posts of a post collection to their keys, moves their legacy vote histories
+// into the vote snapshots and ensures its indexes.
+// It fails if a legacy post cannot be migrated, since the repository only reads and writes posts by key.
+func migrateCollection(ctx context.Context, collection *mongo.Collection, source string, snapshots repository.SnapshotRepository, policy repository.DriftPolicy) error {
+	result, err := repository.MigratePostKeys(ctx, collection, source, false)
+	if err != nil {
+		return err
+	}
+	if result.Documents > 0 {
+		log.Printf("Migrated %s: %d legacy documents into %d posts, %d duplicates merged, %d failed",
+			collection.Name(), result.Documents, result.Posts, result.Merged, result.Failed)
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("%d legacy posts could not be migrated, first error: %v", result.Failed, result.Errors[0])
+	}
+
+	history, err := repository.MigrateVoteHistory(ctx, collection, snapshots, false)
+	if err != nil {
+		return err
+	}
+	if history.Posts > 0 {
+		log.Printf("Migrated the vote histories of %d posts of %s into %d snapshots, %d failed",
+			history.Posts, collection.Name(), history.Snapshots, history.Failed)
+	}
+	if history.Failed > 0 {
+		return fmt.Errorf("%d vote histories could not be migrated, first error: %v", history.Failed, history.Errors[0])
+	}
+
+	return repository.EnsureIndexes(ctx, collection, repository.PostIndexes, policy)
+}
+
+// postCollections are the collections storing the posts of every trend source.
+var postCollections = []struct {
+	Name   string // Name of the collection
+	Source string // Trend source whose posts the collection stores
+}{
+	{"reddit_posts", services.SourceReddit},
+	{"hackernews_posts", services.SourceHackerNews},
+	{"feed_posts", services.SourceFeed},
+	{"mastodon_posts", services.SourceMastodon},
+}
+
+// buildTargets creates the scheduler targets for the trend sources enabled in TREND_SOURCES (default "reddit").
+//
+// Every target records vote snapshots in the given repository.
+// Reddit gets one target per subreddit of the watchlist, fetching the listing of opts, stored in reddit_posts with
+// comments in reddit_comments.
+// Hacker News fetches the HACKERNEWS_LIST story list (default "top") from HACKERNEWS_API_URL, stored in hackernews_posts.
+// Feeds poll the RSS and Atom feeds listed in FEED_URLS, stored in feed_posts.
+// Mastodon reads the trends and the MASTODON_HASHTAGS timelines of MASTODON_INSTANCE, stored in mastodon_posts,
+// with the trending hashtags in mastodon_tags.
+func buildTargets(db *mongo.Database, snapshots repository.SnapshotRepository, redditClient *services.RedditClient, opts services.ListingOptions) []scheduler.Target {
+	var targets []scheduler.Target
+	for _, name := range config.GetEnvList("TREND_SOURCES", []string{services.SourceReddit}) {
+		switch name {
+		case services.SourceReddit:
+			subreddits, err := config.GetRedditSubreddits()
+			if err != nil {
+				log.Fatalf("Failed to configure Reddit sources: %v", err)
+			}
+			for _, subreddit := range subreddits {
+				source, err := services.NewRedditSource(redditClient, subreddit, opts)
+				if err != nil {
+					log.Fatalf("Failed to configure Reddit source: %v", err)
+				}
+				targets = append(targets, scheduler.Target{
+					Source:    source,
+					Posts:     repository.NewMongoPostRepository(db.Collection("reddit_posts")),
+					Snapshots: snapshots,
+					Comments:  db.Collection("reddit_comments"),
+				})
+			}
+		case services.SourceHackerNews:
+			source, err := services.NewHackerNewsSource(
+				config.GetEnv("HACKERNEWS_API_URL", services.HackerNewsAPIURL),
+				config.GetEnv("HACKERNEWS_LIST", "top"),
+				config.GetEnvInt("HACKERNEWS_MAX_ITEMS", 30),
+				nil,
+			)
+			if err != nil {
+				log.Fatalf("Failed to configure Hacker News source: %v", err)
+			}
+			targets = append(targets, scheduler.Target{
+				Source:    source,
+				Posts:     repository.NewMongoPostRepository(db.Collection("hackernews_posts")),
+				Snapshots: snapshots,
+			})
+		case services.SourceFeed:
+			urls := config.GetEnvList("FEED_URLS", nil)
+			if len(urls) == 0 {
+				log.Fatalf("TREND_SOURCES enables feeds but FEED_URLS is empty")
+			}
+			targets = append(targets, scheduler.Target{
+				Source:    services.NewFeedSource(urls, nil),
+				Posts:     repository.NewMongoPostRepository(db.Collection("feed_posts")),
+				Snapshots: snapshots,
+			})
+		case services.SourceMastodon:
+			source, err := services.NewMastodonSource(
+				config.GetEnv("MASTODON_INSTANCE", "https://mastodon.social"),
+				config.GetEnvList("MASTODON_HASHTAGS", nil),
+				config.GetEnvInt("MASTODON_LIMIT", 20),
+				nil,
+			)
+			if err != nil {
+				log.Fatalf("Failed to configure Mastodon source: %v", err)
+			}
+			targets = append(targets, scheduler.Target{
+				Source:    source,
+				Posts:     repository.NewMongoPostRepository(db.Collection("mastodon_posts")),
+				Snapshots: snapshots,
+				Tags:      db.Collection("mastodon_tags"),
+			})
+		default:
+			log.Fatalf("Unknown trend source %q in TREND_SOURCES", name)
+		}
+	}
+	return targets
+}
+
+// snapshotRepository creates the vote snapshot collections if they do not exist yet and returns their repository.
+// Vote snapshots of every source share one time-series collection, since post keys include the source,
+// and are rolled up into hourly and daily collections by the maintenance job.
+func snapshotRepository(ctx context.Context, db *mongo.Database) (repository.SnapshotRepository, error) {
+	raw, err := repository.EnsureSnapshotCollection(ctx, db, "vote_snapshots")
+	if err != nil {
+		return nil, err
+	}
+	hourly, err := repository.EnsureRollupCollection(ctx, db, "vote_snapshots_hourly")
+	if err != nil {
+		return nil, err
+	}
+	daily, err := repository.EnsureRollupCollection(ctx, db, "vote_snapshots_daily")
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewMongoSnapshotRepository(raw, hourly, daily), nil
+}
+
+// buildMaintenanceTask creates the "maintenance" task applying the retention policy of RETENTION_RAW, RETENTION_HOURLY
+// and RETENTION_INACTIVE to the vote snapshots and to every post collection, daily at 03:15 UTC unless the schedule
+// configuration has a "maintenance" job. With MAINTENANCE_DRY_RUN set, runs only report what they would do.
+// Every run records its report in the job run.
+func buildMaintenanceTask(db *mongo.Database, snapshots repository.SnapshotRepository) scheduler.Task {
+	policy := services.RetentionPolicyFromEnv()
+	if err := policy.Validate(); err != nil {
+		log.Fatalf("Invalid retention policy: %v", err)
+	}
+	dryRun := config.GetEnvBool("MAINTENANCE_DRY_RUN", false)
+	stores := maintenanceStores(db)
+
+	return scheduler.Task{
+		Name: "maintenance",
+		Run: func(ctx context.Context, run *models.JobRun) error {
+			report, err := services.RunMaintenance(ctx, snapshots, stores, policy, time.Now(), dryRun)
+			run.Maintenance = report
+			return err
+		},
+		Schedule: config.JobSchedule{Cron: "15 3 * * *"},
+	}
+}
+
+// maintenanceStores returns the post collections maintained by the maintenance job, with the Reddit comments.
+func maintenanceStores(db *mongo.Database) []services.PostStore {
+	var stores []services.PostStore
+	for _, posts := range postCollections {
+		store := services.PostStore{Posts: repository.NewMongoPostRepository(db.Collection(posts.Name))}
+		if posts.Source == services.SourceReddit {
+			store.Comments = db.Collection("reddit_comments")
+		}
+		stores = append(stores, store)
+	}
+	return stores
 }
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/xml"
+)
+
+// RSSFeed represents an RSS 2.0 document.
+type RSSFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Channel RSSChannel `xml:"channel"` // The single channel of the feed
+}
+
+// RSSChannel represents the channel of an RSS 2.0 feed.
+type RSSChannel struct {
+	Title string    `xml:"title"` // Title of the channel
+	Link  string    `xml:"link"`  // URL of the website the channel belongs to
+	Items []RSSItem `xml:"item"`  // Entries of the channel, newest first
+}
+
+// RSSItem represents an entry of an RSS 2.0 channel.
+type RSSItem struct {
+	Title       string `xml:"title"`                                    // Title of the entry
+	Link        string `xml:"link"`                                     // URL of the entry
+	GUID        string `xml:"guid"`                                     // Globally unique identifier of the entry
+	PubDate     string `xml:"pubDate"`                                  // Publication date in RFC 822 format
+	Author      string `xml:"author"`                                   // Email address of the author
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"` // Name of the author (Dublin Core)
+	Description string `xml:"description"`                              // Summary or body of the entry
+	Comments    string `xml:"comments"`                                 // URL of the entry's comment page
+}
+
+// AtomFeed represents an Atom 1.0 document.
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"feed"`  // Matches <feed> in any namespace, since some feeds omit the Atom namespace
+	Title   string      `xml:"title"` // Title of the feed
+	Links   []AtomLink  `xml:"link"`  // Links of the feed
+	Entries []AtomEntry `xml:"entry"` // Entries of the feed
+}
+
+// AtomEntry represents an entry of an Atom 1.0 feed.
+type AtomEntry struct {
+	ID        string       `xml:"id"`        // Permanent, universally unique identifier of the entry
+	Title     string       `xml:"title"`     // Title of the entry
+	Links     []AtomLink   `xml:"link"`      // Links of the entry
+	Published string       `xml:"published"` // Publication time in RFC 3339 format
+	Updated   string       `xml:"updated"`   // Last update time in RFC 3339 format
+	Authors   []AtomPerson `xml:"author"`    // Authors of the entry
+	Summary   string       `xml:"summary"`   // Summary of the entry
+	Content   string       `xml:"content"`   // Body of the entry
+}
+
+// AtomLink represents a link element of an Atom feed or entry.
+type AtomLink struct {
+	Href string `xml:"href,attr"` // Target URL of the link
+	Rel  string `xml:"rel,attr"`  // Relation of the link, "alternate" when omitted
+	Type string `xml:"type,attr"` // Media type of the target
+}
+
+// AtomPerson represents an author or contributor of an Atom feed or entry.
+type AtomPerson struct {
+	Name string `xml:"name"` // Name of the person
+}
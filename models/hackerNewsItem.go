@@ -0,0 +1,17 @@
+package models
+
+// HackerNewsItem represents an item as returned by the Hacker News API (/v0/item/{id}.json).
+// Stories, comments, jobs and polls share this structure; fields that do not apply to a type are omitted by the API.
+type HackerNewsItem struct {
+	ID          int    `json:"id"`          // Unique identifier of the item
+	Type        string `json:"type"`        // Type of the item (story, comment, job, poll or pollopt)
+	By          string `json:"by"`          // Username of the item's author
+	Time        int64  `json:"time"`        // Creation time of the item as a Unix timestamp
+	Title       string `json:"title"`       // Title of the story, job or poll
+	URL         string `json:"url"`         // Outbound URL of the story, empty for Ask HN and similar text posts
+	Text        string `json:"text"`        // Body of the item in HTML
+	Score       int    `json:"score"`       // Score of the story or poll
+	Descendants int    `json:"descendants"` // Total number of comments of the story or poll
+	Dead        bool   `json:"dead"`        // Whether the item is dead (flagged or killed)
+	Deleted     bool   `json:"deleted"`     // Whether the item was deleted
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Statuses of a job run.
+const (
+	JobRunRunning = "running" // The run has started and not finished yet
+	JobRunSuccess = "success" // The run finished without error
+	JobRunFailed  = "failed"  // The run finished with an error
+)
+
+// JobRun represents a single execution of a scheduler job, as recorded in the job_runs collection.
+type JobRun struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`                            // Unique identifier of the run
+	JobName       string             `bson:"job_name" json:"job_name"`                           // Name of the job that ran
+	Status        string             `bson:"status" json:"status"`                               // Status of the run (running, success or failed)
+	StartedAt     time.Time          `bson:"started_at" json:"started_at"`                       // Time the run started
+	FinishedAt    *time.Time         `bson:"finished_at" json:"finished_at"`                     // Time the run finished, nil while running
+	DurationMs    int64              `bson:"duration_ms" json:"duration_ms"`                     // Duration of the run in milliseconds
+	PostsFetched  int                `bson:"posts_fetched" json:"posts_fetched"`                 // Number of posts returned by the source
+	PostsSkipped  int                `bson:"posts_skipped" json:"posts_skipped"`                 // Number of items the source had to skip
+	PostsUpserted int                `bson:"posts_upserted" json:"posts_upserted"`               // Number of posts written to the database
+	PostsFailed   int                `bson:"posts_failed" json:"posts_failed"`                   // Number of posts whose write failed
+	Error         string             `bson:"error,omitempty" json:"error,omitempty"`             // Error that made the run fail
+	Maintenance   *MaintenanceReport `bson:"maintenance,omitempty" json:"maintenance,omitempty"` // What a maintenance run did or, in dry-run mode, would do
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// JobState represents the operator-controlled state of a scheduler job, as stored in the job_states collection.
+// It is shared by every replica, so that a pause survives restarts and a change of leader.
+type JobState struct {
+	Name      string    `bson:"_id" json:"name"`              // Name of the job, one document per job
+	Paused    bool      `bson:"paused" json:"paused"`         // Whether scheduled runs are skipped
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"` // Time the state was last changed
+}
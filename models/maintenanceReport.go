@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MaintenanceReport describes what a run of the maintenance job did to the stored vote history and posts.
+// In dry-run mode nothing is written and the counts are what the run would have done.
+type MaintenanceReport struct {
+	DryRun          bool      `bson:"dry_run" json:"dry_run"`                   // Whether the run only reported its changes
+	RawCutoff       time.Time `bson:"raw_cutoff" json:"raw_cutoff"`             // Raw snapshots observed before this time are rolled up hourly
+	HourlyCutoff    time.Time `bson:"hourly_cutoff" json:"hourly_cutoff"`       // Hourly points before this time are rolled up daily
+	InactiveCutoff  time.Time `bson:"inactive_cutoff" json:"inactive_cutoff"`   // Posts last observed before this time are deleted
+	RawCompacted    int       `bson:"raw_compacted" json:"raw_compacted"`       // Number of raw snapshots rolled up and deleted
+	HourlyWritten   int       `bson:"hourly_written" json:"hourly_written"`     // Number of hourly points written
+	HourlyCompacted int       `bson:"hourly_compacted" json:"hourly_compacted"` // Number of hourly points rolled up and deleted
+	DailyWritten    int       `bson:"daily_written" json:"daily_written"`       // Number of daily points written
+	PostsDeleted    int       `bson:"posts_deleted" json:"posts_deleted"`       // Number of inactive posts deleted
+	SeriesDeleted   int       `bson:"series_deleted" json:"series_deleted"`     // Number of snapshots and points of inactive posts deleted
+	CommentsDeleted int       `bson:"comments_deleted" json:"comments_deleted"` // Number of comments of inactive posts deleted
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// MastodonStatus represents a status (toot) as returned by the Mastodon API.
+type MastodonStatus struct {
+	ID              string          `json:"id"`               // Identifier of the status on the instance
+	CreatedAt       time.Time       `json:"created_at"`       // Creation time of the status
+	URL             string          `json:"url"`              // URL of the status' HTML page
+	URI             string          `json:"uri"`              // Federated URI of the status
+	Content         string          `json:"content"`          // Body of the status in HTML
+	SpoilerText     string          `json:"spoiler_text"`     // Content warning shown before the body
+	Sensitive       bool            `json:"sensitive"`        // Whether the status is marked as sensitive
+	Language        string          `json:"language"`         // ISO 639 language code of the status
+	Account         MastodonAccount `json:"account"`          // Author of the status
+	ReblogsCount    int             `json:"reblogs_count"`    // Number of boosts
+	FavouritesCount int             `json:"favourites_count"` // Number of favourites
+	RepliesCount    int             `json:"replies_count"`    // Number of replies
+	Reblog          *MastodonStatus `json:"reblog"`           // The boosted status, when this status is a boost
+	Card            *MastodonCard   `json:"card"`             // Preview card of the first link in the status
+}
+
+// MastodonAccount represents the author of a Mastodon status.
+type MastodonAccount struct {
+	Acct        string `json:"acct"`         // Webfinger address, without the domain for local accounts
+	Username    string `json:"username"`     // Username on the account's instance
+	DisplayName string `json:"display_name"` // Display name of the account
+}
+
+// MastodonCard represents the preview card of a link in a Mastodon status.
+type MastodonCard struct {
+	URL   string `json:"url"`   // URL of the linked page
+	Title string `json:"title"` // Title of the linked page
+	Image string `json:"image"` // Preview image of the linked page
+}
+
+// MastodonTag represents a trending hashtag as returned by /api/v1/trends/tags.
+type MastodonTag struct {
+	Name    string               `json:"name"`    // Name of the hashtag, without the leading #
+	URL     string               `json:"url"`     // URL of the hashtag's page on the instance
+	History []MastodonTagHistory `json:"history"` // Daily usage statistics, most recent day first
+}
+
+// MastodonTagHistory represents the usage of a hashtag on a given day.
+// Mastodon encodes all of these values as strings.
+type MastodonTagHistory struct {
+	Day      string `json:"day"`      // Start of the day as a Unix timestamp
+	Uses     string `json:"uses"`     // Number of statuses using the hashtag that day
+	Accounts string `json:"accounts"` // Number of accounts using the hashtag that day
+}
+
+// TrendingTag represents the structure of a trending Mastodon hashtag in the database.
+// Hashtags are not statuses, so they are stored apart from the posts, one document per instance and hashtag.
+type TrendingTag struct {
+	ID         string    `bson:"_id"`         // Instance host and lowercased hashtag (e.g., mastodon.social:golang)
+	Instance   string    `bson:"instance"`    // Host of the instance the hashtag trends on
+	Name       string    `bson:"name"`        // Name of the hashtag, without the leading #
+	URL        string    `bson:"url"`         // URL of the hashtag's page on the instance
+	Rank       int       `bson:"rank"`        // Position of the hashtag in the trends when it was last observed
+	Uses       int       `bson:"uses"`        // Number of statuses using the hashtag on the most recent day
+	Accounts   int       `bson:"accounts"`    // Number of accounts using the hashtag on the most recent day
+	InsertedAt time.Time `bson:"inserted_at"` // Timestamp of when the hashtag was last stored
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"strings"
+)
+
+// redditPostPrefix is the type prefix of Reddit link fullnames (e.g., t3_abc123).
+const redditPostPrefix = "t3_"
+
+// PostKey returns the key identifying a post in the database, stored as its _id.
+//
+// Reddit posts are keyed by their fullname (t3_ followed by the base-36 post ID), which is how the Reddit API
+// refers to them. Posts of other platforms are keyed by their source and platform ID (e.g., hackernews:123), so
+// that IDs of different platforms never collide. An empty platform ID yields an empty key.
+func PostKey(source string, platformID string) string {
+	if platformID == "" {
+		return ""
+	}
+	if source == "reddit" {
+		// Accept fullnames as well as bare IDs
+		return redditPostPrefix + strings.TrimPrefix(platformID, redditPostPrefix)
+	}
+	return source + ":" + platformID
+}
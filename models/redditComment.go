@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RedditCommentData represents the data of a t1 (comment) thing as returned by the API.
+// Replies are either an empty string or a nested Listing, so they are left undecoded.
+type RedditCommentData struct {
+	ID         string          `json:"id"`          // Base36 identifier of the comment
+	Name       string          `json:"name"`        // Fullname of the comment (e.g., t1_abc123)
+	ParentID   string          `json:"parent_id"`   // Fullname of the parent comment or post
+	LinkID     string          `json:"link_id"`     // Fullname of the post the comment belongs to
+	Author     string          `json:"author"`      // Username of the comment's author
+	Body       string          `json:"body"`        // Body of the comment in markdown
+	Score      FlexInt         `json:"score"`       // Net score of the comment
+	CreatedUTC float64         `json:"created_utc"` // Creation time of the comment as a Unix timestamp
+	Depth      int             `json:"depth"`       // Depth of the comment in the thread (0 for top-level comments)
+	Replies    json.RawMessage `json:"replies"`     // Nested Listing of replies, or an empty string
+}
+
+// RedditComment represents the structure of a Reddit comment in the database.
+// Comments are stored flattened, each one linked to its post and to its parent.
+type RedditComment struct {
+	ID             string    `bson:"_id"`             // Base36 identifier of the comment
+	PostID         string    `bson:"post_id"`         // Identifier of the post the comment belongs to
+	ParentID       string    `bson:"parent_id"`       // Fullname of the parent comment, or of the post for top-level comments
+	Author         string    `bson:"author"`          // Username of the comment's author
+	Body           string    `bson:"body"`            // Body of the comment
+	Score          int       `bson:"score"`           // Net score of the comment
+	Depth          int       `bson:"depth"`           // Depth of the comment in the thread (0 for top-level comments)
+	CreatedAt      time.Time `bson:"created_utc"`     // Creation time of the comment on Reddit
+	Sentiment      string    `bson:"sentiment"`       // Sentiment of the body (positive, negative or neutral)
+	SentimentScore float64   `bson:"sentiment_score"` // VADER compound score of the body
+	InsertedAt     time.Time `bson:"inserted_at"`     // Timestamp of when the comment was last stored
+}
+
+// CommentSentiment represents the aggregate sentiment of the comments stored for a post.
+type CommentSentiment struct {
+	Count     int       `bson:"count"`      // Number of comments analyzed
+	Average   float64   `bson:"average"`    // Average VADER compound score of the comments
+	Positive  int       `bson:"positive"`   // Number of positive comments
+	Negative  int       `bson:"negative"`   // Number of negative comments
+	Neutral   int       `bson:"neutral"`    // Number of neutral comments
+	Label     string    `bson:"label"`      // Sentiment label of the average score
+	UpdatedAt time.Time `bson:"updated_at"` // Timestamp of the last aggregation
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// RedditListing represents Reddit's Listing envelope, which wraps every paginated response from the API.
+// The children of the listing are kept as raw things so each one can be decoded independently.
+type RedditListing struct {
+	Kind string            `json:"kind"` // The kind of the envelope (always "Listing" for listings)
+	Data RedditListingData `json:"data"` // The payload of the listing
+}
+
+// RedditListingData represents the payload of a Reddit Listing.
+// It includes the pagination cursors and the things contained in the current page.
+type RedditListingData struct {
+	After    string        `json:"after"`    // Fullname of the last thing in the page, used to fetch the next page
+	Before   string        `json:"before"`   // Fullname of the first thing in the page, used to fetch the previous page
+	Dist     int           `json:"dist"`     // Number of things returned in the page
+	Children []RedditThing `json:"children"` // The things contained in the page
+}
+
+// RedditThing represents a single Reddit "thing" inside a listing.
+// The data is left undecoded so that one malformed child does not prevent decoding of the others.
+type RedditThing struct {
+	Kind string          `json:"kind"` // The kind of the thing (e.g., t3 for links, t1 for comments)
+	Data json.RawMessage `json:"data"` // The raw data of the thing, decoded according to its kind
+}
+
+// RedditLink represents the data of a t3 (link) thing, i.e. a Reddit post as returned by the API.
+type RedditLink struct {
+	ID    string  `json:"id"`    // Base36 identifier of the post
+	Name  string  `json:"name"`  // Fullname of the post (e.g., t3_abc123)
+	Title string  `json:"title"` // The title of the post
+	Ups   FlexInt `json:"ups"`   // Number of upvotes for the post
+	Downs FlexInt `json:"downs"` // Number of downvotes for the post
+
+	Subreddit             string `json:"subreddit"`               // Name of the subreddit the post was made in (e.g., golang)
+	SubredditNamePrefixed string `json:"subreddit_name_prefixed"` // Prefixed name of the subreddit (e.g., r/golang)
+
+	Permalink     string  `json:"permalink"`       // Path of the post's comment page, relative to reddit.com
+	URL           string  `json:"url"`             // Outbound URL of the post, or the comment page for self posts
+	Author        string  `json:"author"`          // Username of the post's author
+	CreatedUTC    float64 `json:"created_utc"`     // Creation time of the post as a Unix timestamp
+	NumComments   FlexInt `json:"num_comments"`    // Number of comments on the post
+	UpvoteRatio   float64 `json:"upvote_ratio"`    // Ratio of upvotes to total votes
+	Score         FlexInt `json:"score"`           // Net score of the post
+	Over18        bool    `json:"over_18"`         // Whether the post is marked NSFW
+	Spoiler       bool    `json:"spoiler"`         // Whether the post is marked as a spoiler
+	Stickied      bool    `json:"stickied"`        // Whether the post is stickied in its subreddit
+	LinkFlairText string  `json:"link_flair_text"` // Text of the post's flair, if any
+	Domain        string  `json:"domain"`          // Domain of the outbound URL (e.g., self.golang for self posts)
+	IsSelf        bool    `json:"is_self"`         // Whether the post is a text (self) post
+	Selftext      string  `json:"selftext"`        // Body of a text post in markdown
+	Thumbnail     string  `json:"thumbnail"`       // Thumbnail URL, or a keyword such as "self" or "default"
+}
+
+// FlexInt is an integer that tolerates the different ways Reddit encodes numeric fields.
+// It accepts JSON integers, floating point numbers, numeric strings and null, which decodes to zero.
+type FlexInt int
+
+// UnmarshalJSON decodes a FlexInt from a JSON number, numeric string or null.
+func (f *FlexInt) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" || raw == "" {
+		*f = 0
+		return nil
+	}
+
+	// Strip the quotes of numeric strings such as "42"
+	raw = strings.Trim(raw, `"`)
+	if raw == "" {
+		*f = 0
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return err
+	}
+
+	*f = FlexInt(value)
+	return nil
+}
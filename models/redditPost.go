@@ -14,14 +14,35 @@ type VoteHistoryEntry struct {
 // RedditPost represents the structure of a Reddit post in the database.
 // It includes various fields relevant to a Reddit post, such as its title, vote counts, and history of votes.
 type RedditPost struct {
-	ID              string             `bson:"_id,omitempty"`    // Unique identifier for the post (auto-generated if omitted)
-	Title           string             `bson:"title"`            // The title of the Reddit post
-	Upvotes         int                `bson:"upvotes"`          // Total number of upvotes for the post
-	Downvotes       int                `bson:"downvotes"`        // Total number of downvotes for the post
-	Subreddit       string             `bson:"subreddit"`        // The subreddit where the post was made
-	PermaLink       string             `bson:"perma_link"`       // Permanent link to the post on Reddit
-	URL             string             `bson:"url"`              // URL of the post or associated content
-	InsertedAt      time.Time          `bson:"inserted_at"`      // Timestamp of when the post was inserted into the database
-	UpvoteHistory   []VoteHistoryEntry `bson:"upvote_history"`   // History of upvotes on the post
-	DownvoteHistory []VoteHistoryEntry `bson:"downvote_history"` // History of downvotes on the post
+	ID                string             `bson:"_id"`                         // Key of the post, see PostKey (e.g., t3_abc123 or hackernews:123)
+	PostID            string             `bson:"id"`                          // Identifier of the post on its platform (e.g., abc123)
+	Source            string             `bson:"source"`                      // Platform the post was fetched from (e.g., reddit, hackernews)
+	Title             string             `bson:"title"`                       // The title of the Reddit post
+	Upvotes           int                `bson:"upvotes"`                     // Total number of upvotes for the post
+	Downvotes         int                `bson:"downvotes"`                   // Total number of downvotes for the post
+	Subreddit         string             `bson:"subreddit"`                   // The subreddit where the post was made
+	SubredditPrefixed string             `bson:"subreddit_name_prefixed"`     // The prefixed name of the subreddit (e.g., r/golang)
+	Community         string             `bson:"community"`                   // The community the post belongs to on other platforms (e.g., a feed title)
+	PermaLink         string             `bson:"perma_link"`                  // Permanent link to the post on Reddit
+	URL               string             `bson:"url"`                         // URL of the post or associated content
+	Author            string             `bson:"author"`                      // Username of the post's author
+	CreatedAt         time.Time          `bson:"created_utc"`                 // Creation time of the post on Reddit
+	NumComments       int                `bson:"num_comments"`                // Number of comments on the post
+	UpvoteRatio       float64            `bson:"upvote_ratio"`                // Ratio of upvotes to total votes
+	Score             int                `bson:"score"`                       // Net score of the post
+	Over18            bool               `bson:"over_18"`                     // Whether the post is marked NSFW
+	Spoiler           bool               `bson:"spoiler"`                     // Whether the post is marked as a spoiler
+	Stickied          bool               `bson:"stickied"`                    // Whether the post is stickied
+	FlairText         string             `bson:"link_flair_text"`             // Text of the post's flair, if any
+	Domain            string             `bson:"domain"`                      // Domain of the outbound URL
+	IsSelf            bool               `bson:"is_self"`                     // Whether the post is a text (self) post
+	Selftext          string             `bson:"selftext"`                    // Body of a text post
+	Thumbnail         string             `bson:"thumbnail"`                   // Thumbnail URL, empty when the post has none
+	Sentiment         string             `bson:"sentiment"`                   // Sentiment of the title (positive, negative or neutral)
+	CommentSentiment  *CommentSentiment  `bson:"comment_sentiment,omitempty"` // Aggregate sentiment of the post's stored comments
+	InsertedAt        time.Time          `bson:"inserted_at"`                 // Timestamp of when the post was inserted into the database
+	Rank              int                `bson:"rank"`                        // Position of the post in the listing when it was last observed
+	Listing           string             `bson:"listing"`                     // Listing the post was last observed in (e.g., hot, top:week)
+	UpvoteHistory     []VoteHistoryEntry `bson:"upvote_history"`              // Legacy upvote counts, moved into the vote snapshots at startup
+	DownvoteHistory   []VoteHistoryEntry `bson:"downvote_history"`            // Legacy downvote counts, moved into the vote snapshots at startup
 }
@@ -1,11 +1,40 @@
 package models
 
+import (
+	"time"
+)
+
 // TrendingPost represents a structure for a trending post in the application.
 // It includes fields that capture the essential information about the trending post,
 // such as its ID, name, and volume of votes.
+// It is the common item model every trend source normalizes its items into; fields a platform
+// has no equivalent for are left empty.
 type TrendingPost struct {
 	ID         string `json:"id"`          // Unique identifier for the trending post
+	Source     string `json:"source"`      // Platform the post was fetched from (e.g., reddit, hackernews)
 	Name       string `json:"name"`        // Name or title of the trending post
 	VolumeUp   int    `json:"volume_up"`   // Number of upvotes for the trending post
 	VolumeDown int    `json:"volume_down"` // Number of downvotes for the trending post
+	Rank       int    `json:"rank"`        // Absolute position of the post in the listing it was fetched from (1-based)
+	Listing    string `json:"listing"`     // Listing the post was observed in (e.g., hot, top:week)
+
+	Subreddit         string `json:"subreddit"`          // Name of the subreddit the post was made in (e.g., golang)
+	SubredditPrefixed string `json:"subreddit_prefixed"` // Prefixed name of the subreddit (e.g., r/golang)
+	Community         string `json:"community"`          // Community the post belongs to on other platforms (e.g., a feed title)
+
+	Permalink   string    `json:"permalink"`    // Absolute URL of the post's comment page
+	URL         string    `json:"url"`          // Outbound URL of the post
+	Author      string    `json:"author"`       // Username of the post's author
+	CreatedAt   time.Time `json:"created_at"`   // Creation time of the post
+	NumComments int       `json:"num_comments"` // Number of comments on the post
+	UpvoteRatio float64   `json:"upvote_ratio"` // Ratio of upvotes to total votes
+	Score       int       `json:"score"`        // Net score of the post
+	Over18      bool      `json:"over_18"`      // Whether the post is marked NSFW
+	Spoiler     bool      `json:"spoiler"`      // Whether the post is marked as a spoiler
+	Stickied    bool      `json:"stickied"`     // Whether the post is stickied
+	FlairText   string    `json:"flair_text"`   // Text of the post's flair, if any
+	Domain      string    `json:"domain"`       // Domain of the outbound URL
+	IsSelf      bool      `json:"is_self"`      // Whether the post is a text (self) post
+	Selftext    string    `json:"selftext"`     // Body of a text post
+	Thumbnail   string    `json:"thumbnail"`    // Thumbnail URL, empty when the post has none
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// SchedulerLease represents the lease held by the replica that runs the scheduled jobs,
+// as stored in the scheduler_leases collection.
+type SchedulerLease struct {
+	Name       string    `bson:"_id" json:"name"`                // Name of the lease, one document per lease
+	Owner      string    `bson:"owner" json:"owner"`             // Identifier of the replica holding the lease
+	AcquiredAt time.Time `bson:"acquired_at" json:"acquired_at"` // Time the current owner acquired the lease
+	RenewedAt  time.Time `bson:"renewed_at" json:"renewed_at"`   // Time of the owner's last heartbeat
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`   // Time after which another replica may take over
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// VoteSnapshot represents the votes of a post observed by a single scrape, as stored in the vote_snapshots
+// time-series collection. The post key is the collection's metaField and ObservedAt its timeField.
+type VoteSnapshot struct {
+	PostKey     string    `bson:"post_id" json:"post_id"`           // Key of the post (see PostKey)
+	ObservedAt  time.Time `bson:"observed_at" json:"observed_at"`   // Time of the scrape that observed the post
+	Score       int       `bson:"score" json:"score"`               // Net score of the post
+	Upvotes     int       `bson:"ups" json:"ups"`                   // Number of upvotes of the post
+	NumComments int       `bson:"comments" json:"comments"`         // Number of comments of the post
+	Rank        int       `bson:"rank" json:"rank"`                 // Position of the post in the listing it was observed in
+	UpvoteRatio float64   `bson:"upvote_ratio" json:"upvote_ratio"` // Ratio of upvotes to total votes
+}
+
+// SeriesPoint summarizes the vote snapshots of a post within one interval of a series.
+// Values are those of the last snapshot of the interval; the score also has its minimum, maximum and average.
+type SeriesPoint struct {
+	Time        time.Time `bson:"time" json:"time"`                 // Start of the interval, or the snapshot time for raw series
+	Samples     int       `bson:"samples" json:"samples"`           // Number of snapshots in the interval
+	Score       int       `bson:"score" json:"score"`               // Last net score of the interval
+	ScoreMin    int       `bson:"score_min" json:"score_min"`       // Lowest net score of the interval
+	ScoreMax    int       `bson:"score_max" json:"score_max"`       // Highest net score of the interval
+	ScoreAvg    float64   `bson:"score_avg" json:"score_avg"`       // Average net score of the interval
+	Upvotes     int       `bson:"ups" json:"ups"`                   // Last number of upvotes of the interval
+	NumComments int       `bson:"comments" json:"comments"`         // Last number of comments of the interval
+	Rank        int       `bson:"rank" json:"rank"`                 // Last rank of the interval
+	UpvoteRatio float64   `bson:"upvote_ratio" json:"upvote_ratio"` // Last upvote ratio of the interval
+}
+
+// VoteRollup is a SeriesPoint of a post stored in the hourly or daily rollup collection of the vote snapshots,
+// once the snapshots it summarizes have aged out of the finer resolution.
+type VoteRollup struct {
+	ID          string           `bson:"_id"`     // Post key and interval start, so that rolling up an interval again replaces it
+	PostKey     string           `bson:"post_id"` // Key of the post (see PostKey)
+	SeriesPoint `bson:",inline"` // Summary of the interval
+}
@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CheckPostRepository runs the conformance suite of the PostRepository contract against an empty repository and
+// returns an error describing the first violation, in the style of testing/fstest.TestFS. Every implementation
+// must pass it, so that code tested against MemoryPostRepository behaves the same against MongoPostRepository.
+//
+// The suite writes posts whose platform IDs start with "conformance-"; the repository must not hold any other posts.
+func CheckPostRepository(ctx context.Context, repo PostRepository) error {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context, repo PostRepository) error
+	}{
+		{"empty repository", checkEmpty},
+		{"insert", checkInsert},
+		{"votes", checkVotes},
+		{"comment sentiment", checkCommentSentiment},
+		{"find", checkFind},
+		{"aggregate", checkAggregate},
+		{"isolation", checkIsolation},
+		{"retention", checkRetention},
+	}
+
+	// The checks build on each other's posts, so they run in order and stop at the first failure
+	for _, c := range checks {
+		if err := c.check(ctx, repo); err != nil {
+			return fmt.Errorf("%s: %v", c.name, err)
+		}
+	}
+	return nil
+}
+
+// conformanceStart is the InsertedAt time of the first upsert of the suite. Times have millisecond precision,
+// which is what MongoDB stores.
+var conformanceStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// conformanceKey returns the key of a post of the suite.
+func conformanceKey(id string) string {
+	return models.PostKey("reddit", "conformance-"+id)
+}
+
+// conformancePosts returns the posts of the first upsert of the suite.
+func conformancePosts() []models.RedditPost {
+	post := func(id string, subreddit string, score int, comments int, sentiment string, over18 bool) models.RedditPost {
+		return models.RedditPost{
+			ID:          conformanceKey(id),
+			PostID:      "conformance-" + id,
+			Source:      "reddit",
+			Title:       "Post " + id,
+			Upvotes:     score,
+			Subreddit:   subreddit,
+			Author:      "author-" + id,
+			CreatedAt:   conformanceStart.Add(-time.Hour),
+			NumComments: comments,
+			Score:       score,
+			Over18:      over18,
+			Sentiment:   sentiment,
+			InsertedAt:  conformanceStart,
+			Rank:        len(id),
+		}
+	}
+
+	return []models.RedditPost{
+		post("a", "golang", 100, 10, "positive", false),
+		post("b", "golang", 50, 30, "negative", false),
+		post("c", "rust", 50, 5, "positive", true),
+		post("d", "rust", 10, 0, "neutral", false),
+	}
+}
+
+// checkEmpty verifies that missing posts are reported as nil.
+func checkEmpty(ctx context.Context, repo PostRepository) error {
+	post, err := repo.Get(ctx, conformanceKey("a"))
+	if err != nil {
+		return fmt.Errorf("Get: %v", err)
+	}
+	if post != nil {
+		return fmt.Errorf("Get of a missing post returned %+v, want nil", post)
+	}
+	return nil
+}
+
+// checkInsert verifies that new posts are inserted once, without history, and that posts without a key fail.
+func checkInsert(ctx context.Context, repo PostRepository) error {
+	posts := conformancePosts()
+	duplicate := posts[0]
+	duplicate.Title = "Duplicate"
+	posts = append(posts, duplicate, models.RedditPost{PostID: "conformance-e", Title: "No key"})
+
+	result, err := repo.Upsert(ctx, posts)
+	if err != nil {
+		return fmt.Errorf("Upsert: %v", err)
+	}
+	if result.Inserted != 4 || result.Modified != 0 || result.Failed != 1 || len(result.Errors) != 1 {
+		return fmt.Errorf("Upsert returned %+v, want 4 inserted, 0 modified and 1 failed", result)
+	}
+
+	post, err := repo.Get(ctx, conformanceKey("a"))
+	if err != nil {
+		return fmt.Errorf("Get: %v", err)
+	}
+	switch {
+	case post == nil:
+		return fmt.Errorf("Get of an inserted post returned nil")
+	case post.ID != conformanceKey("a") || post.PostID != "conformance-a" || post.Title != "Post a" ||
+		post.Upvotes != 100 || post.Subreddit != "golang":
+		return fmt.Errorf("Get returned %+v, want the first occurrence of the post", post)
+	case !post.CreatedAt.Equal(conformanceStart.Add(-time.Hour)) || !post.InsertedAt.Equal(conformanceStart):
+		return fmt.Errorf("Get returned created %v and inserted %v, want %v and %v",
+			post.CreatedAt, post.InsertedAt, conformanceStart.Add(-time.Hour), conformanceStart)
+	case len(post.UpvoteHistory) != 0 || len(post.DownvoteHistory) != 0 || post.CommentSentiment != nil:
+		return fmt.Errorf("Get returned history or comment sentiment for a new post: %+v", post)
+	}
+	return nil
+}
+
+// checkVotes verifies that vote changes update stored posts without writing vote history
+// and that unchanged posts are not counted as modified.
+func checkVotes(ctx context.Context, repo PostRepository) error {
+	later := conformanceStart.Add(5 * time.Minute)
+	posts := conformancePosts()
+	posts[0].Upvotes = 120
+	posts[0].Downvotes = 3
+	posts[0].InsertedAt = later
+
+	// Only the first post changed; the others are written again unchanged
+	result, err := repo.Upsert(ctx, posts)
+	if err != nil {
+		return fmt.Errorf("Upsert: %v", err)
+	}
+	if result.Inserted != 0 || result.Modified != 1 || result.Failed != 0 {
+		return fmt.Errorf("Upsert returned %+v, want 0 inserted, 1 modified and 0 failed", result)
+	}
+
+	post, err := repo.Get(ctx, conformanceKey("a"))
+	if err != nil || post == nil {
+		return fmt.Errorf("Get returned %v, %v", post, err)
+	}
+	if post.Upvotes != 120 || post.Downvotes != 3 || !post.InsertedAt.Equal(later) {
+		return fmt.Errorf("Get returned %d upvotes, %d downvotes inserted at %v, want 120, 3 and %v",
+			post.Upvotes, post.Downvotes, post.InsertedAt, later)
+	}
+	if len(post.UpvoteHistory) != 0 || len(post.DownvoteHistory) != 0 {
+		return fmt.Errorf("changed post has history %+v, %+v", post.UpvoteHistory, post.DownvoteHistory)
+	}
+	return nil
+}
+
+// checkCommentSentiment verifies that the comment sentiment is stored, survives upserts and is ignored for missing posts.
+func checkCommentSentiment(ctx context.Context, repo PostRepository) error {
+	sentiment := &models.CommentSentiment{Count: 3, Average: 0.25, Positive: 2, Neutral: 1, Label: "positive", UpdatedAt: conformanceStart}
+	if err := repo.SetCommentSentiment(ctx, conformanceKey("b"), sentiment); err != nil {
+		return fmt.Errorf("SetCommentSentiment: %v", err)
+	}
+	if err := repo.SetCommentSentiment(ctx, conformanceKey("missing"), sentiment); err != nil {
+		return fmt.Errorf("SetCommentSentiment of a missing post: %v", err)
+	}
+	if post, err := repo.Get(ctx, conformanceKey("missing")); err != nil || post != nil {
+		return fmt.Errorf("SetCommentSentiment of a missing post created %+v (%v)", post, err)
+	}
+
+	// Upserting the post again must keep the sentiment
+	posts := conformancePosts()
+	posts[1].Downvotes = 7
+	posts[1].InsertedAt = conformanceStart.Add(20 * time.Minute)
+	if _, err := repo.Upsert(ctx, posts[1:2]); err != nil {
+		return fmt.Errorf("Upsert: %v", err)
+	}
+
+	post, err := repo.Get(ctx, conformanceKey("b"))
+	if err != nil || post == nil {
+		return fmt.Errorf("Get returned %v, %v", post, err)
+	}
+	if post.CommentSentiment == nil || post.CommentSentiment.Count != 3 || post.CommentSentiment.Label != "positive" ||
+		!post.CommentSentiment.UpdatedAt.Equal(conformanceStart) {
+		return fmt.Errorf("comment sentiment is %+v, want %+v", post.CommentSentiment, sentiment)
+	}
+	if post.Downvotes != 7 {
+		return fmt.Errorf("Get returned %d downvotes, want 7", post.Downvotes)
+	}
+	return nil
+}
+
+// checkFind verifies filtering, sorting with ties broken by key, and pagination.
+func checkFind(ctx context.Context, repo PostRepository) error {
+	minScore := 50
+	nsfw := true
+	notNSFW := false
+
+	cases := []struct {
+		name  string
+		query PostQuery
+		want  []string // Expected post IDs without the "conformance-" prefix, in order
+	}{
+		{"all", PostQuery{}, []string{"a", "b", "c", "d"}},
+		{"subreddit", PostQuery{Filter: PostFilter{Subreddit: "rust"}}, []string{"c", "d"}},
+		{"sentiment and source", PostQuery{Filter: PostFilter{Source: "reddit", Sentiment: "positive"}}, []string{"a", "c"}},
+		{"min score", PostQuery{Filter: PostFilter{MinScore: &minScore}}, []string{"a", "b", "c"}},
+		{"flag", PostQuery{Filter: PostFilter{Over18: &nsfw}}, []string{"c"}},
+		{"flag and min score", PostQuery{Filter: PostFilter{Over18: &notNSFW, MinScore: &minScore}}, []string{"a", "b"}},
+		{"no match", PostQuery{Filter: PostFilter{Author: "nobody"}}, []string{}},
+		{"sort descending with ties", PostQuery{Sort: SortScore, Descending: true}, []string{"a", "b", "c", "d"}},
+		{"sort ascending", PostQuery{Sort: SortComments}, []string{"d", "c", "a", "b"}},
+		{"page", PostQuery{Sort: SortScore, Descending: true, Skip: 1, Limit: 2}, []string{"b", "c"}},
+		{"page past the end", PostQuery{Skip: 10, Limit: 2}, []string{}},
+	}
+
+	for _, c := range cases {
+		posts, err := repo.Find(ctx, c.query)
+		if err != nil {
+			return fmt.Errorf("%s: Find: %v", c.name, err)
+		}
+		if posts == nil {
+			return fmt.Errorf("%s: Find returned nil, want an empty slice", c.name)
+		}
+
+		got := make([]string, len(posts))
+		for i, post := range posts {
+			got[i] = post.PostID[len("conformance-"):]
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			return fmt.Errorf("%s: Find returned %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	if _, err := repo.Find(ctx, PostQuery{Sort: "bogus"}); err == nil {
+		return fmt.Errorf("Find with an invalid sort field succeeded")
+	}
+	return nil
+}
+
+// checkAggregate verifies the groups, their order and their summaries.
+func checkAggregate(ctx context.Context, repo PostRepository) error {
+	groups, err := repo.Aggregate(ctx, PostFilter{}, GroupSubreddit)
+	if err != nil {
+		return fmt.Errorf("Aggregate: %v", err)
+	}
+	want := []PostGroup{
+		{Key: "golang", Count: 2, AverageScore: 75, Positive: 1, Negative: 1},
+		{Key: "rust", Count: 2, AverageScore: 30, Positive: 1, Neutral: 1},
+	}
+	if err := compareGroups(groups, want); err != nil {
+		return err
+	}
+
+	minScore := 50
+	groups, err = repo.Aggregate(ctx, PostFilter{MinScore: &minScore}, GroupSentiment)
+	if err != nil {
+		return fmt.Errorf("Aggregate: %v", err)
+	}
+	want = []PostGroup{
+		{Key: "positive", Count: 2, AverageScore: 75, Positive: 2},
+		{Key: "negative", Count: 1, AverageScore: 50, Negative: 1},
+	}
+	if err := compareGroups(groups, want); err != nil {
+		return err
+	}
+
+	if _, err := repo.Aggregate(ctx, PostFilter{}, "bogus"); err == nil {
+		return fmt.Errorf("Aggregate with an invalid group field succeeded")
+	}
+	return nil
+}
+
+// compareGroups reports the first difference between two lists of groups, comparing averages with a tolerance.
+func compareGroups(got []PostGroup, want []PostGroup) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("Aggregate returned %+v, want %+v", got, want)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Key != w.Key || g.Count != w.Count || g.Positive != w.Positive || g.Negative != w.Negative ||
+			g.Neutral != w.Neutral || math.Abs(g.AverageScore-w.AverageScore) > 1e-9 {
+			return fmt.Errorf("Aggregate returned %+v at index %d, want %+v", g, i, w)
+		}
+	}
+	return nil
+}
+
+// checkIsolation verifies that modifying a returned post does not modify the stored post.
+func checkIsolation(ctx context.Context, repo PostRepository) error {
+	post, err := repo.Get(ctx, conformanceKey("b"))
+	if err != nil || post == nil || post.CommentSentiment == nil {
+		return fmt.Errorf("Get returned %v, %v", post, err)
+	}
+	post.Title = "Modified"
+	post.CommentSentiment.Count = -1
+
+	stored, err := repo.Get(ctx, conformanceKey("b"))
+	if err != nil || stored == nil || stored.CommentSentiment == nil {
+		return fmt.Errorf("Get returned %v, %v", stored, err)
+	}
+	if stored.Title != "Post b" || stored.CommentSentiment.Count != 3 {
+		return fmt.Errorf("modifying a returned post changed the stored post to %+v", stored)
+	}
+	return nil
+}
+
+// checkRetention verifies that inactive posts are listed by key with their identity only, and that they can be deleted.
+func checkRetention(ctx context.Context, repo PostRepository) error {
+	// Posts a, c and d were last stored before b
+	inactive, err := repo.Inactive(ctx, conformanceStart.Add(10*time.Minute))
+	if err != nil {
+		return fmt.Errorf("Inactive: %v", err)
+	}
+	want := []string{conformanceKey("a"), conformanceKey("c"), conformanceKey("d")}
+	got := make([]string, len(inactive))
+	for i, post := range inactive {
+		got[i] = post.ID
+		if post.PostID == "" || post.Source != "reddit" || post.InsertedAt.IsZero() || post.Title != "" {
+			return fmt.Errorf("Inactive returned %+v, want only the identity of the post", post)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("Inactive returned %v, want %v", got, want)
+	}
+
+	deleted, err := repo.Delete(ctx, []string{conformanceKey("a"), conformanceKey("missing")})
+	if err != nil {
+		return fmt.Errorf("Delete: %v", err)
+	}
+	if deleted != 1 {
+		return fmt.Errorf("Delete returned %d, want 1", deleted)
+	}
+	if post, err := repo.Get(ctx, conformanceKey("a")); err != nil || post != nil {
+		return fmt.Errorf("Get of a deleted post returned %+v (%v)", post, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"slices"
+	"time"
+)
+
+// HistoryMigrationResult reports the outcome of MigrateVoteHistory.
+type HistoryMigrationResult struct {
+	Posts     int     // Number of posts that had a legacy vote history
+	Snapshots int     // Number of vote snapshots recorded from the legacy histories
+	Failed    int     // Number of posts whose history could not be migrated
+	Errors    []error // Errors of the failed posts
+}
+
+// legacyHistory is the vote history that earlier versions kept on a post.
+type legacyHistory struct {
+	ID              string                    `bson:"_id"`              // Key of the post
+	Upvotes         int                       `bson:"upvotes"`          // Stored number of upvotes of the post
+	Downvotes       int                       `bson:"downvotes"`        // Stored number of downvotes of the post
+	UpvoteHistory   []models.VoteHistoryEntry `bson:"upvote_history"`   // Upvote counts, recorded whenever they changed
+	DownvoteHistory []models.VoteHistoryEntry `bson:"downvote_history"` // Downvote counts, recorded whenever they changed
+}
+
+// MigrateVoteHistory moves the upvote_history and downvote_history arrays that earlier versions kept on the posts
+// of the collection into vote snapshots, then removes them from the posts.
+//
+// Every entry of either history becomes a snapshot of the post at its timestamp, with the upvote and downvote
+// counts last recorded at that time; the fields the histories did not track are left zero. The histories only
+// recorded changes, so entries older than the first one of the other count are skipped, unless the other count
+// never changed and is taken from the post. Snapshots older than the raw retention are rolled up by the next
+// maintenance run like any other.
+// Posts must already be keyed by models.PostKey, so MigratePostKeys has to run first.
+// A post's snapshots are recorded before its histories are removed, and snapshots already stored at the same
+// times are not recorded again, so a run after an interrupted one does not duplicate them.
+// With dryRun set it only reports what it would do.
+func MigrateVoteHistory(ctx context.Context, collection *mongo.Collection, snapshots SnapshotRepository, dryRun bool) (*HistoryMigrationResult, error) {
+	filter := bson.M{
+		"_id": bson.M{"$type": "string"},
+		"$or": bson.A{
+			bson.M{"upvote_history": bson.M{"$exists": true}},
+			bson.M{"downvote_history": bson.M{"$exists": true}},
+		},
+	}
+	projection := bson.M{"_id": 1, "upvotes": 1, "downvotes": 1, "upvote_history": 1, "downvote_history": 1}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find legacy vote histories in %s: %v", collection.Name(), err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	result := &HistoryMigrationResult{}
+	for cursor.Next(ctx) {
+		var history legacyHistory
+		if err := cursor.Decode(&history); err != nil {
+			return result, fmt.Errorf("failed to decode legacy vote history in %s: %v", collection.Name(), err)
+		}
+		result.Posts++
+
+		migrated, err := unrecordedSnapshots(ctx, snapshots, historySnapshots(history))
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("post %s: %v", history.ID, err))
+			continue
+		}
+		if dryRun {
+			result.Snapshots += len(migrated)
+			continue
+		}
+		if err := snapshots.Record(ctx, migrated); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("post %s: %v", history.ID, err))
+			continue
+		}
+		result.Snapshots += len(migrated)
+
+		unset := bson.M{"$unset": bson.M{"upvote_history": "", "downvote_history": ""}}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": history.ID}, unset); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("failed to remove the vote history of post %s from %s: %v", history.ID, collection.Name(), err))
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return result, fmt.Errorf("failed to read legacy vote histories from %s: %v", collection.Name(), err)
+	}
+	return result, nil
+}
+
+// historySnapshots turns the legacy vote history of a post into one snapshot per distinct timestamp, oldest first.
+// A count whose history is empty never changed and is taken from the post; otherwise it is unknown before its
+// first entry, so no snapshot is made until both counts are known.
+func historySnapshots(history legacyHistory) []models.VoteSnapshot {
+	// vote is a history entry tagged with the count it records
+	type vote struct {
+		entry models.VoteHistoryEntry
+		up    bool
+	}
+	votes := make([]vote, 0, len(history.UpvoteHistory)+len(history.DownvoteHistory))
+	for _, entry := range history.UpvoteHistory {
+		votes = append(votes, vote{entry: entry, up: true})
+	}
+	for _, entry := range history.DownvoteHistory {
+		votes = append(votes, vote{entry: entry})
+	}
+	slices.SortStableFunc(votes, func(a, b vote) int {
+		return a.entry.Timestamp.Compare(b.entry.Timestamp)
+	})
+
+	upvotes, downvotes := history.Upvotes, history.Downvotes
+	upvotesKnown, downvotesKnown := len(history.UpvoteHistory) == 0, len(history.DownvoteHistory) == 0
+
+	var snapshots []models.VoteSnapshot
+	for i, v := range votes {
+		if v.up {
+			upvotes, upvotesKnown = v.entry.Value, true
+		} else {
+			downvotes, downvotesKnown = v.entry.Value, true
+		}
+		// Entries recorded by the same scrape share their timestamp and make a single snapshot
+		if i+1 < len(votes) && votes[i+1].entry.Timestamp.Equal(v.entry.Timestamp) {
+			continue
+		}
+		if !upvotesKnown || !downvotesKnown {
+			continue
+		}
+
+		snapshots = append(snapshots, models.VoteSnapshot{
+			PostKey:    history.ID,
+			ObservedAt: v.entry.Timestamp,
+			Score:      upvotes - downvotes,
+			Upvotes:    upvotes,
+		})
+	}
+	return snapshots
+}
+
+// unrecordedSnapshots drops the snapshots of a post that are already stored at the same time, so that migrating
+// a post again after an interrupted run does not record its snapshots twice.
+func unrecordedSnapshots(ctx context.Context, snapshots SnapshotRepository, migrated []models.VoteSnapshot) ([]models.VoteSnapshot, error) {
+	if len(migrated) == 0 {
+		return migrated, nil
+	}
+
+	query := SeriesQuery{
+		From: migrated[0].ObservedAt.Truncate(time.Millisecond),
+		To:   migrated[len(migrated)-1].ObservedAt.Add(time.Millisecond),
+	}
+	stored, err := snapshots.Series(ctx, migrated[0].PostKey, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the recorded vote snapshots: %v", err)
+	}
+	recorded := make(map[int64]bool, len(stored))
+	for _, point := range stored {
+		recorded[point.Time.UnixMilli()] = true
+	}
+
+	return slices.DeleteFunc(migrated, func(snapshot models.VoteSnapshot) bool {
+		return recorded[snapshot.ObservedAt.UnixMilli()]
+	}), nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHistorySnapshots(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		history legacyHistory
+		want    []models.VoteSnapshot
+	}{
+		{
+			name: "both histories",
+			history: legacyHistory{
+				ID:      "t3_a",
+				Upvotes: 20,
+				UpvoteHistory: []models.VoteHistoryEntry{
+					{Value: 20, Timestamp: start.Add(3 * time.Hour)},
+					{Value: 8, Timestamp: start}, // Older than the first downvote count
+					{Value: 10, Timestamp: start.Add(time.Hour)},
+				},
+				Downvotes: 5,
+				DownvoteHistory: []models.VoteHistoryEntry{
+					{Value: 3, Timestamp: start.Add(time.Hour)}, // Recorded by the same scrape as an upvote count
+					{Value: 5, Timestamp: start.Add(2 * time.Hour)},
+				},
+			},
+			want: []models.VoteSnapshot{
+				{PostKey: "t3_a", ObservedAt: start.Add(time.Hour), Score: 7, Upvotes: 10},
+				{PostKey: "t3_a", ObservedAt: start.Add(2 * time.Hour), Score: 5, Upvotes: 10},
+				{PostKey: "t3_a", ObservedAt: start.Add(3 * time.Hour), Score: 15, Upvotes: 20},
+			},
+		},
+		{
+			name: "unchanged downvotes",
+			history: legacyHistory{
+				ID:      "t3_b",
+				Upvotes: 12,
+				UpvoteHistory: []models.VoteHistoryEntry{
+					{Value: 4, Timestamp: start},
+					{Value: 12, Timestamp: start.Add(time.Hour)},
+				},
+				Downvotes: 2,
+			},
+			want: []models.VoteSnapshot{
+				{PostKey: "t3_b", ObservedAt: start, Score: 2, Upvotes: 4},
+				{PostKey: "t3_b", ObservedAt: start.Add(time.Hour), Score: 10, Upvotes: 12},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots := historySnapshots(tt.history)
+			if len(snapshots) != len(tt.want) {
+				t.Fatalf("got %d snapshots, want %d: %+v", len(snapshots), len(tt.want), snapshots)
+			}
+			for i := range tt.want {
+				if snapshots[i] != tt.want[i] {
+					t.Fatalf("snapshot %d is %+v, want %+v", i, snapshots[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUnrecordedSnapshots(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	migrated := []models.VoteSnapshot{
+		{PostKey: "t3_a", ObservedAt: start, Upvotes: 1},
+		{PostKey: "t3_a", ObservedAt: start.Add(time.Hour), Upvotes: 2},
+	}
+
+	// An interrupted run already recorded the first snapshot
+	snapshots := NewMemorySnapshotRepository()
+	if err := snapshots.Record(ctx, migrated[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := unrecordedSnapshots(ctx, snapshots, slices.Clone(migrated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] != migrated[1] {
+		t.Fatalf("got %+v, want only the second snapshot", remaining)
+	}
+}
+
+func TestMigrateVoteHistory(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	collection := db.Collection("posts")
+	observed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := collection.InsertMany(ctx, []any{
+		bson.M{"_id": "t3_a", "id": "a", "upvote_history": bson.A{bson.M{"value": 10, "timestamp": observed}}},
+		bson.M{"_id": "t3_b", "id": "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := NewMemorySnapshotRepository()
+	dryRun, err := MigrateVoteHistory(ctx, collection, snapshots, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Posts != 1 || dryRun.Snapshots != 1 {
+		t.Fatalf("dry run reported %+v, want 1 post with 1 snapshot", dryRun)
+	}
+	if series, _ := snapshots.Series(ctx, "t3_a", SeriesQuery{}); len(series) != 0 {
+		t.Fatalf("dry run recorded %+v", series)
+	}
+
+	result, err := MigrateVoteHistory(ctx, collection, snapshots, false)
+	if err != nil || result.Posts != 1 || result.Snapshots != 1 || result.Failed != 0 {
+		t.Fatalf("migration reported %+v (%v), want 1 post with 1 snapshot", result, err)
+	}
+	series, err := snapshots.Series(ctx, "t3_a", SeriesQuery{})
+	if err != nil || len(series) != 1 || series[0].Upvotes != 10 || !series[0].Time.Equal(observed) {
+		t.Fatalf("series is %+v (%v), want the upvotes of the history", series, err)
+	}
+	if count, _ := collection.CountDocuments(ctx, bson.M{"upvote_history": bson.M{"$exists": true}}); count != 0 {
+		t.Fatalf("%d posts still have a vote history", count)
+	}
+
+	// A second run finds nothing left to migrate
+	if again, err := MigrateVoteHistory(ctx, collection, snapshots, false); err != nil || again.Posts != 0 {
+		t.Fatalf("second migration reported %+v (%v), want nothing to migrate", again, err)
+	}
+}
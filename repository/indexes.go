@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"sort"
+	"strings"
+)
+
+// namespaceNotFound is the code of the error MongoDB returns when listing the indexes of a missing collection.
+const namespaceNotFound = 26
+
+// IndexSpec declares an index of a collection.
+type IndexSpec struct {
+	Name   string // Name of the index, used to match it against the existing indexes
+	Keys   bson.D // Indexed fields with their direction (1 or -1) or "text" for text indexes
+	Unique bool   // Whether the index enforces unique values
+}
+
+// PostIndexes are the indexes of every post collection:
+//   - the platform ID serves the key migration; it is not unique, since posts are keyed by _id and
+//     MigratePostKeys writes a post under its key before deleting its legacy documents
+//   - subreddit and inserted_at serve the listings of a subreddit, newest first
+//   - inserted_at alone serves the lookup of inactive posts by the maintenance job
+//   - sentiment and score serve the sentiment filter sorted by score
+//   - the title text index serves full-text search
+var PostIndexes = []IndexSpec{
+	{Name: "id", Keys: bson.D{{Key: "id", Value: 1}}},
+	{Name: "subreddit_inserted_at", Keys: bson.D{{Key: "subreddit", Value: 1}, {Key: "inserted_at", Value: -1}}},
+	{Name: "inserted_at", Keys: bson.D{{Key: "inserted_at", Value: 1}}},
+	{Name: "sentiment_score", Keys: bson.D{{Key: "sentiment", Value: 1}, {Key: "score", Value: -1}}},
+	{Name: "title_text", Keys: bson.D{{Key: "title", Value: "text"}}},
+}
+
+// IndexDrift describes a difference between the declared and the existing indexes of a collection.
+type IndexDrift struct {
+	Index   string // Name of the index
+	Problem string // What differs: "missing", "different" or "undeclared"
+	Detail  string // Human-readable description of the difference
+}
+
+// Kinds of index drift.
+const (
+	DriftMissing    = "missing"    // A declared index does not exist
+	DriftDifferent  = "different"  // An index exists with the declared name but other keys or options
+	DriftUndeclared = "undeclared" // An index exists that is not declared
+)
+
+// DriftPolicy selects what EnsureIndexes does when existing indexes differ from the declared ones.
+type DriftPolicy string
+
+const (
+	DriftLog  DriftPolicy = "log"  // Log the differences and continue
+	DriftFail DriftPolicy = "fail" // Return an error
+)
+
+// ParseDriftPolicy converts a configuration value into a DriftPolicy.
+func ParseDriftPolicy(value string) (DriftPolicy, error) {
+	switch policy := DriftPolicy(strings.ToLower(value)); policy {
+	case DriftLog, DriftFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid index drift policy %q (want log or fail)", value)
+}
+
+// existingIndex is an index as reported by listIndexes.
+type existingIndex struct {
+	Name    string `bson:"name"`    // Name of the index
+	Key     bson.D `bson:"key"`     // Indexed fields; text indexes report the internal _fts and _ftsx fields
+	Unique  bool   `bson:"unique"`  // Whether the index enforces unique values
+	Weights bson.M `bson:"weights"` // Fields of a text index with their weights
+}
+
+// CheckIndexes compares the existing indexes of the collection with the declared ones.
+// The default _id index is ignored.
+func CheckIndexes(ctx context.Context, collection *mongo.Collection, specs []IndexSpec) ([]IndexDrift, error) {
+	existing, err := listIndexes(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []IndexDrift
+	declared := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		declared[spec.Name] = true
+
+		index, ok := existing[spec.Name]
+		if !ok {
+			drifts = append(drifts, IndexDrift{Index: spec.Name, Problem: DriftMissing, Detail: "index does not exist"})
+			continue
+		}
+		if got, want := describeKeys(logicalKeys(index)), describeKeys(spec.Keys); got != want || index.Unique != spec.Unique {
+			drifts = append(drifts, IndexDrift{
+				Index:   spec.Name,
+				Problem: DriftDifferent,
+				Detail:  fmt.Sprintf("has keys %s (unique %t), declared %s (unique %t)", got, index.Unique, want, spec.Unique),
+			})
+		}
+	}
+
+	// Report the undeclared indexes in a stable order
+	var names []string
+	for name := range existing {
+		if !declared[name] && name != "_id_" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		drifts = append(drifts, IndexDrift{
+			Index:   name,
+			Problem: DriftUndeclared,
+			Detail:  fmt.Sprintf("has keys %s but is not declared", describeKeys(logicalKeys(existing[name]))),
+		})
+	}
+
+	return drifts, nil
+}
+
+// EnsureIndexes creates the declared indexes missing from the collection and checks the others for drift.
+//
+// Indexes that exist but differ from their declaration, and undeclared indexes, are left untouched: with DriftLog
+// they are logged, with DriftFail they make EnsureIndexes return an error. A missing index that cannot be created,
+// for instance a unique index over duplicate values, is handled the same way. MigrateIndexes repairs the drift.
+func EnsureIndexes(ctx context.Context, collection *mongo.Collection, specs []IndexSpec, policy DriftPolicy) error {
+	drifts, err := CheckIndexes(ctx, collection, specs)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, drift := range drifts {
+		if drift.Problem == DriftMissing {
+			if err := createIndex(ctx, collection, specFor(specs, drift.Index)); err != nil {
+				problems = append(problems, err.Error())
+			}
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("index %s is %s: %s", drift.Index, drift.Problem, drift.Detail))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	if policy == DriftFail {
+		return fmt.Errorf("indexes of %s drifted: %s", collection.Name(), strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		log.Printf("Index drift in %s: %s", collection.Name(), problem)
+	}
+	return nil
+}
+
+// MigrateIndexes makes the indexes of the collection match the declared ones: it creates the missing indexes and
+// drops and recreates the indexes that differ from their declaration. Undeclared indexes are only dropped if
+// dropUndeclared is set, since they may have been added by hand for a reason.
+// It returns the drift it found, or an error if an index cannot be created or dropped.
+func MigrateIndexes(ctx context.Context, collection *mongo.Collection, specs []IndexSpec, dropUndeclared bool) ([]IndexDrift, error) {
+	drifts, err := CheckIndexes(ctx, collection, specs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the undeclared indexes first, since one of them may hold the keys of a declared index
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Problem == DriftUndeclared && drifts[j].Problem != DriftUndeclared
+	})
+
+	for _, drift := range drifts {
+		switch drift.Problem {
+		case DriftMissing:
+			err = createIndex(ctx, collection, specFor(specs, drift.Index))
+		case DriftDifferent:
+			if err = dropIndex(ctx, collection, drift.Index); err == nil {
+				err = createIndex(ctx, collection, specFor(specs, drift.Index))
+			}
+		case DriftUndeclared:
+			if !dropUndeclared {
+				log.Printf("Keeping undeclared index %s of %s", drift.Index, collection.Name())
+				continue
+			}
+			err = dropIndex(ctx, collection, drift.Index)
+		}
+		if err != nil {
+			return drifts, err
+		}
+	}
+
+	return drifts, nil
+}
+
+// listIndexes retrieves the existing indexes of the collection, keyed by name.
+func listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]existingIndex, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceNotFound {
+		return map[string]existingIndex{}, nil // The collection does not exist yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes of %s: %v", collection.Name(), err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	var indexes []existingIndex
+	if err = cursor.All(ctx, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to decode indexes of %s: %v", collection.Name(), err)
+	}
+
+	byName := make(map[string]existingIndex, len(indexes))
+	for _, index := range indexes {
+		byName[index.Name] = index
+	}
+	return byName, nil
+}
+
+// createIndex creates a declared index.
+func createIndex(ctx context.Context, collection *mongo.Collection, spec IndexSpec) error {
+	model := mongo.IndexModel{
+		Keys:    spec.Keys,
+		Options: options.Index().SetName(spec.Name).SetUnique(spec.Unique),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("failed to create index %s of %s: %v", spec.Name, collection.Name(), err)
+	}
+	log.Printf("Created index %s of %s", spec.Name, collection.Name())
+	return nil
+}
+
+// dropIndex drops an index by name.
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+		return fmt.Errorf("failed to drop index %s of %s: %v", name, collection.Name(), err)
+	}
+	log.Printf("Dropped index %s of %s", name, collection.Name())
+	return nil
+}
+
+// specFor returns the declared index with the given name.
+func specFor(specs []IndexSpec, name string) IndexSpec {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return spec
+		}
+	}
+	return IndexSpec{}
+}
+
+// logicalKeys returns the keys of an existing index the way they are declared.
+// MongoDB reports a text index as the internal _fts and _ftsx keys, with the indexed fields in its weights.
+func logicalKeys(index existingIndex) bson.D {
+	var keys bson.D
+	for _, key := range index.Key {
+		switch key.Key {
+		case "_fts":
+			var fields []string
+			for field := range index.Weights {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				keys = append(keys, bson.E{Key: field, Value: "text"})
+			}
+		case "_ftsx":
+			// Internal companion of _fts
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// describeKeys formats index keys as "{field: direction, ...}". Formatting the directions makes the int32 and
+// double directions reported by MongoDB compare equal to declared ints.
+func describeKeys(keys bson.D) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s: %v", key.Key, key.Value)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"backend/models"
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryPostRepository is the PostRepository keeping posts in memory, for tests and local development.
+// It is safe for concurrent use. Returned posts are copies, so callers cannot modify the stored posts.
+type MemoryPostRepository struct {
+	mu    sync.RWMutex                 // Guards posts
+	posts map[string]models.RedditPost // Stored posts, keyed by post key
+}
+
+// NewMemoryPostRepository creates an empty MemoryPostRepository.
+func NewMemoryPostRepository() *MemoryPostRepository {
+	return &MemoryPostRepository{posts: make(map[string]models.RedditPost)}
+}
+
+// Upsert stores the posts, keeping the vote histories and comment sentiment of stored posts.
+func (r *MemoryPostRepository) Upsert(ctx context.Context, posts []models.RedditPost) (*UpsertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &UpsertResult{}
+	unique, invalid := uniquePosts(posts)
+	for _, err := range invalid {
+		result.Failed++
+		result.Errors = append(result.Errors, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, post := range unique {
+		post = storedTimes(copyPost(post))
+		stored, ok := r.posts[post.ID]
+		if !ok {
+			// New posts start without history, like upserted MongoDB documents
+			post.CommentSentiment = nil
+			post.UpvoteHistory = nil
+			post.DownvoteHistory = nil
+			r.posts[post.ID] = post
+			result.Inserted++
+			continue
+		}
+
+		// Keep the stored histories and comment sentiment
+		post.CommentSentiment = stored.CommentSentiment
+		post.UpvoteHistory = stored.UpvoteHistory
+		post.DownvoteHistory = stored.DownvoteHistory
+
+		// Like MongoDB, only count the post as modified if the update changed it
+		if !reflect.DeepEqual(stored, post) {
+			result.Modified++
+		}
+		r.posts[post.ID] = post
+	}
+
+	return result, nil
+}
+
+// Get returns a copy of the post with the given key, or nil if there is none.
+func (r *MemoryPostRepository) Get(ctx context.Context, key string) (*models.RedditPost, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	post, ok := r.posts[key]
+	if !ok {
+		return nil, nil
+	}
+	post = copyPost(post)
+	return &post, nil
+}
+
+// Find returns copies of the posts matching the query.
+func (r *MemoryPostRepository) Find(ctx context.Context, query PostQuery) ([]models.RedditPost, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := sortFields[query.Sort]; query.Sort != "" && !ok {
+		return nil, fmt.Errorf("invalid sort field %q", query.Sort)
+	}
+
+	posts := r.matching(query.Filter)
+
+	// Sort by the requested field, breaking ties on the key
+	slices.SortFunc(posts, func(a, b models.RedditPost) int {
+		if query.Sort != "" {
+			order := compareField(query.Sort, a, b)
+			if query.Descending {
+				order = -order
+			}
+			if order != 0 {
+				return order
+			}
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	// Paginate
+	if query.Skip >= len(posts) {
+		return []models.RedditPost{}, nil
+	}
+	posts = posts[query.Skip:]
+	if query.Limit > 0 && query.Limit < len(posts) {
+		posts = posts[:query.Limit]
+	}
+	return posts, nil
+}
+
+// Aggregate groups the posts matching the filter.
+func (r *MemoryPostRepository) Aggregate(ctx context.Context, filter PostFilter, groupBy GroupField) ([]PostGroup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := groupFields[groupBy]; !ok {
+		return nil, fmt.Errorf("invalid group field %q", groupBy)
+	}
+
+	byKey := make(map[string]*PostGroup)
+	totals := make(map[string]int) // Sum of the scores of every group
+	for _, post := range r.matching(filter) {
+		key := groupKey(groupBy, post)
+		group, ok := byKey[key]
+		if !ok {
+			group = &PostGroup{Key: key}
+			byKey[key] = group
+		}
+
+		group.Count++
+		totals[key] += post.Score
+		switch post.Sentiment {
+		case "positive":
+			group.Positive++
+		case "negative":
+			group.Negative++
+		default:
+			group.Neutral++
+		}
+	}
+
+	groups := []PostGroup{}
+	for key, group := range byKey {
+		group.AverageScore = float64(totals[key]) / float64(group.Count)
+		groups = append(groups, *group)
+	}
+
+	// Largest group first, then by key
+	slices.SortFunc(groups, func(a, b PostGroup) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return groups, nil
+}
+
+// SetCommentSentiment stores the aggregate comment sentiment on the post.
+func (r *MemoryPostRepository) SetCommentSentiment(ctx context.Context, key string, sentiment *models.CommentSentiment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[key]
+	if !ok {
+		return nil
+	}
+	post.CommentSentiment = sentiment
+	r.posts[key] = storedTimes(copyPost(post))
+	return nil
+}
+
+// Inactive returns the identity of the posts last stored before the given time.
+func (r *MemoryPostRepository) Inactive(ctx context.Context, before time.Time) ([]models.RedditPost, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	posts := []models.RedditPost{}
+	for _, post := range r.posts {
+		if post.InsertedAt.Before(before) {
+			posts = append(posts, models.RedditPost{ID: post.ID, PostID: post.PostID, Source: post.Source, InsertedAt: post.InsertedAt})
+		}
+	}
+	slices.SortFunc(posts, func(a, b models.RedditPost) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return posts, nil
+}
+
+// Delete deletes the posts with the given keys.
+func (r *MemoryPostRepository) Delete(ctx context.Context, keys []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := r.posts[key]; ok {
+			delete(r.posts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// matching returns copies of the stored posts matching the filter, in no particular order.
+func (r *MemoryPostRepository) matching(filter PostFilter) []models.RedditPost {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	posts := []models.RedditPost{}
+	for _, post := range r.posts {
+		if matchesFilter(filter, post) {
+			posts = append(posts, copyPost(post))
+		}
+	}
+	return posts
+}
+
+// matchesFilter reports whether a post is selected by the filter.
+func matchesFilter(filter PostFilter, post models.RedditPost) bool {
+	// Exact matches on string fields
+	for _, field := range [][2]string{
+		{filter.Source, post.Source},
+		{filter.Sentiment, post.Sentiment},
+		{filter.Subreddit, post.Subreddit},
+		{filter.Author, post.Author},
+		{filter.Flair, post.FlairText},
+		{filter.Domain, post.Domain},
+	} {
+		if field[0] != "" && field[0] != field[1] {
+			return false
+		}
+	}
+
+	// Boolean flags
+	for _, field := range []struct {
+		want *bool
+		got  bool
+	}{
+		{filter.Over18, post.Over18},
+		{filter.Spoiler, post.Spoiler},
+		{filter.Stickied, post.Stickied},
+		{filter.IsSelf, post.IsSelf},
+	} {
+		if field.want != nil && *field.want != field.got {
+			return false
+		}
+	}
+
+	// Lower bounds on numeric fields
+	if filter.MinScore != nil && post.Score < *filter.MinScore {
+		return false
+	}
+	if filter.MinComments != nil && post.NumComments < *filter.MinComments {
+		return false
+	}
+	return true
+}
+
+// compareField compares two posts by a sort field.
+func compareField(field SortField, a, b models.RedditPost) int {
+	switch field {
+	case SortRank:
+		return cmp.Compare(a.Rank, b.Rank)
+	case SortScore:
+		return cmp.Compare(a.Score, b.Score)
+	case SortUpvotes:
+		return cmp.Compare(a.Upvotes, b.Upvotes)
+	case SortComments:
+		return cmp.Compare(a.NumComments, b.NumComments)
+	case SortCreated:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case SortInserted:
+		return a.InsertedAt.Compare(b.InsertedAt)
+	}
+	return 0
+}
+
+// groupKey returns the value of the grouped field of a post.
+func groupKey(field GroupField, post models.RedditPost) string {
+	switch field {
+	case GroupSource:
+		return post.Source
+	case GroupSentiment:
+		return post.Sentiment
+	case GroupSubreddit:
+		return post.Subreddit
+	case GroupCommunity:
+		return post.Community
+	case GroupDomain:
+		return post.Domain
+	case GroupAuthor:
+		return post.Author
+	}
+	return ""
+}
+
+// copyPost returns a copy of a post that shares no memory with it.
+func copyPost(post models.RedditPost) models.RedditPost {
+	post.UpvoteHistory = slices.Clone(post.UpvoteHistory)
+	post.DownvoteHistory = slices.Clone(post.DownvoteHistory)
+	if post.CommentSentiment != nil {
+		sentiment := *post.CommentSentiment
+		post.CommentSentiment = &sentiment
+	}
+	return post
+}
+
+// storedTimes truncates the times of a post to milliseconds in UTC, which is how MongoDB stores and returns dates.
+// The vote histories and comment sentiment must not be shared with a stored post.
+func storedTimes(post models.RedditPost) models.RedditPost {
+	stored := func(t time.Time) time.Time { return t.Truncate(time.Millisecond).UTC() }
+
+	post.CreatedAt = stored(post.CreatedAt)
+	post.InsertedAt = stored(post.InsertedAt)
+	for i := range post.UpvoteHistory {
+		post.UpvoteHistory[i].Timestamp = stored(post.UpvoteHistory[i].Timestamp)
+	}
+	for i := range post.DownvoteHistory {
+		post.DownvoteHistory[i].Timestamp = stored(post.DownvoteHistory[i].Timestamp)
+	}
+	if post.CommentSentiment != nil {
+		post.CommentSentiment.UpdatedAt = stored(post.CommentSentiment.UpdatedAt)
+	}
+	return post
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryPostRepository(t *testing.T) {
+	if err := CheckPostRepository(context.Background(), NewMemoryPostRepository()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemorySnapshotRepository is the SnapshotRepository keeping snapshots in memory, for tests and local development.
+// It is safe for concurrent use.
+type MemorySnapshotRepository struct {
+	mu        sync.RWMutex                     // Guards snapshots, hourly and daily
+	snapshots map[string][]models.VoteSnapshot // Raw snapshots of every post key, oldest first
+	hourly    map[string][]models.SeriesPoint  // Hourly points of every post key, oldest first
+	daily     map[string][]models.SeriesPoint  // Daily points of every post key, oldest first
+}
+
+// NewMemorySnapshotRepository creates an empty MemorySnapshotRepository.
+func NewMemorySnapshotRepository() *MemorySnapshotRepository {
+	return &MemorySnapshotRepository{
+		snapshots: make(map[string][]models.VoteSnapshot),
+		hourly:    make(map[string][]models.SeriesPoint),
+		daily:     make(map[string][]models.SeriesPoint),
+	}
+}
+
+// Record stores the snapshots, keeping the snapshots of every post ordered by observation time.
+func (r *MemorySnapshotRepository) Record(ctx context.Context, snapshots []models.VoteSnapshot) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, snapshot := range snapshots {
+		if snapshot.PostKey == "" {
+			continue
+		}
+		// Like MongoDB, store times with millisecond precision in UTC
+		snapshot.ObservedAt = snapshot.ObservedAt.Truncate(time.Millisecond).UTC()
+
+		series := r.snapshots[snapshot.PostKey]
+		i, _ := slices.BinarySearchFunc(series, snapshot.ObservedAt, func(s models.VoteSnapshot, t time.Time) int {
+			if s.ObservedAt.After(t) {
+				return 1
+			}
+			return -1 // Snapshots observed at the same time keep their insertion order
+		})
+		r.snapshots[snapshot.PostKey] = slices.Insert(series, i, snapshot)
+	}
+	return nil
+}
+
+// Series summarizes the snapshots and rolled-up points of a post.
+func (r *MemorySnapshotRepository) Series(ctx context.Context, postKey string, query SeriesQuery) ([]models.SeriesPoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inRange := func(t time.Time) bool {
+		return (query.From.IsZero() || !t.Before(query.From)) && (query.To.IsZero() || t.Before(query.To))
+	}
+
+	var points []models.SeriesPoint
+	for _, snapshot := range r.snapshots[postKey] {
+		if inRange(snapshot.ObservedAt) {
+			points = append(points, snapshotPoint(snapshot))
+		}
+	}
+	for _, rollups := range [][]models.SeriesPoint{r.hourly[postKey], r.daily[postKey]} {
+		for _, point := range rollups {
+			if inRange(point.Time) {
+				points = append(points, point)
+			}
+		}
+	}
+	return combinePoints(points, query.Resolution), nil
+}
+
+// Compact rolls the old raw snapshots up into hourly points and the old hourly points up into daily points.
+func (r *MemorySnapshotRepository) Compact(ctx context.Context, cutoffs CompactionCutoffs, dryRun bool) (*CompactionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &CompactionResult{}
+
+	// Raw snapshots into hourly points
+	for key, snapshots := range r.snapshots {
+		var old []models.SeriesPoint
+		for _, snapshot := range snapshots {
+			if snapshot.ObservedAt.Before(cutoffs.Raw) {
+				old = append(old, snapshotPoint(snapshot))
+			}
+		}
+		if len(old) == 0 {
+			continue
+		}
+
+		rolled := combinePoints(old, time.Hour)
+		result.RawCompacted += len(old)
+		result.HourlyWritten += len(rolled)
+		if dryRun {
+			continue
+		}
+		r.hourly[key] = replacePoints(r.hourly[key], rolled)
+		r.snapshots[key] = slices.Clone(snapshots[len(old):]) // Snapshots are ordered, so the old ones come first
+	}
+
+	// Hourly points into daily points; in dry-run mode the hourly points rolled up above are not counted
+	for key, points := range r.hourly {
+		var old []models.SeriesPoint
+		for _, point := range points {
+			if point.Time.Before(cutoffs.Hourly) {
+				old = append(old, point)
+			}
+		}
+		if len(old) == 0 {
+			continue
+		}
+
+		rolled := combinePoints(old, 24*time.Hour)
+		result.HourlyCompacted += len(old)
+		result.DailyWritten += len(rolled)
+		if dryRun {
+			continue
+		}
+		r.daily[key] = replacePoints(r.daily[key], rolled)
+		r.hourly[key] = slices.Clone(points[len(old):])
+	}
+
+	return result, nil
+}
+
+// Delete deletes the snapshots and points of the posts.
+func (r *MemorySnapshotRepository) Delete(ctx context.Context, postKeys []string, dryRun bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for _, key := range postKeys {
+		deleted += len(r.snapshots[key]) + len(r.hourly[key]) + len(r.daily[key])
+		if !dryRun {
+			delete(r.snapshots, key)
+			delete(r.hourly, key)
+			delete(r.daily, key)
+		}
+	}
+	return deleted, nil
+}
+
+// replacePoints stores rolled-up points in an ordered series, replacing the points with the same time.
+func replacePoints(series []models.SeriesPoint, points []models.SeriesPoint) []models.SeriesPoint {
+	series = slices.DeleteFunc(slices.Clone(series), func(stored models.SeriesPoint) bool {
+		return slices.ContainsFunc(points, func(point models.SeriesPoint) bool { return point.Time.Equal(stored.Time) })
+	})
+	series = append(series, points...)
+	slices.SortStableFunc(series, func(a, b models.SeriesPoint) int {
+		return a.Time.Compare(b.Time)
+	})
+	return series
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemorySnapshotRepository(t *testing.T) {
+	if err := CheckSnapshotRepository(context.Background(), NewMemorySnapshotRepository()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// MongoPostRepository is the PostRepository storing posts in a MongoDB collection.
+type MongoPostRepository struct {
+	collection *mongo.Collection // The collection where posts are stored
+}
+
+// NewMongoPostRepository creates a MongoPostRepository storing posts in the given collection.
+func NewMongoPostRepository(collection *mongo.Collection) *MongoPostRepository {
+	return &MongoPostRepository{collection: collection}
+}
+
+// Upsert writes the posts with a single unordered BulkWrite, so a batch takes one round trip regardless of its size.
+func (r *MongoPostRepository) Upsert(ctx context.Context, posts []models.RedditPost) (*UpsertResult, error) {
+	result := &UpsertResult{}
+	unique, invalid := uniquePosts(posts)
+	for _, err := range invalid {
+		result.Failed++
+		result.Errors = append(result.Errors, err)
+	}
+	if len(unique) == 0 {
+		return result, nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(unique))
+	for _, post := range unique {
+		// Prepare the update for the MongoDB document, leaving the histories and comment sentiment untouched
+		update := bson.M{
+			"$set": bson.M{
+				"id":                      post.PostID,
+				"source":                  post.Source,
+				"title":                   post.Title,
+				"upvotes":                 post.Upvotes,
+				"downvotes":               post.Downvotes,
+				"subreddit":               post.Subreddit,
+				"subreddit_name_prefixed": post.SubredditPrefixed,
+				"community":               post.Community,
+				"perma_link":              post.PermaLink,
+				"url":                     post.URL,
+				"author":                  post.Author,
+				"created_utc":             post.CreatedAt,
+				"num_comments":            post.NumComments,
+				"upvote_ratio":            post.UpvoteRatio,
+				"score":                   post.Score,
+				"over_18":                 post.Over18,
+				"spoiler":                 post.Spoiler,
+				"stickied":                post.Stickied,
+				"link_flair_text":         post.FlairText,
+				"domain":                  post.Domain,
+				"is_self":                 post.IsSelf,
+				"selftext":                post.Selftext,
+				"thumbnail":               post.Thumbnail,
+				"inserted_at":             post.InsertedAt,
+				"rank":                    post.Rank,
+				"listing":                 post.Listing,
+				"sentiment":               post.Sentiment,
+			},
+		}
+
+		// Use upsert to insert or update the document
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": post.ID}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+
+	// An unordered bulk write keeps going after a failed write and lets the server apply the writes in parallel
+	res, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if res != nil {
+		result.Inserted = int(res.UpsertedCount)
+		result.Modified = int(res.ModifiedCount)
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		for _, writeErr := range bulkErr.WriteErrors {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("failed to upsert post %s: %v", unique[writeErr.Index].ID, writeErr.Message))
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to upsert posts into MongoDB: %v", err)
+	}
+
+	return result, nil
+}
+
+// Get retrieves the post with the given key, or nil if there is none.
+func (r *MongoPostRepository) Get(ctx context.Context, key string) (*models.RedditPost, error) {
+	var post models.RedditPost
+	err := r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&post)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve post %s from MongoDB: %v", key, err)
+	}
+	return &post, nil
+}
+
+// Find retrieves the posts matching the query.
+func (r *MongoPostRepository) Find(ctx context.Context, query PostQuery) ([]models.RedditPost, error) {
+	if _, ok := sortFields[query.Sort]; query.Sort != "" && !ok {
+		return nil, fmt.Errorf("invalid sort field %q", query.Sort)
+	}
+
+	// Break ties on the key so that pages never overlap
+	sort := bson.D{}
+	if query.Sort != "" {
+		direction := 1
+		if query.Descending {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: sortFields[query.Sort], Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	findOptions := options.Find().SetSort(sort).SetSkip(int64(query.Skip))
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filterDocument(query.Filter), findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve posts from MongoDB: %v", err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	posts := []models.RedditPost{}
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, fmt.Errorf("failed to decode posts from cursor: %v", err)
+	}
+	return posts, nil
+}
+
+// Aggregate groups the posts matching the filter with an aggregation pipeline.
+func (r *MongoPostRepository) Aggregate(ctx context.Context, filter PostFilter, groupBy GroupField) ([]PostGroup, error) {
+	field, ok := groupFields[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group field %q", groupBy)
+	}
+
+	// countIf counts the posts for which the condition holds
+	countIf := func(condition bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filterDocument(filter)}},
+		{{Key: "$group", Value: bson.D{
+			// A missing field groups with the empty string, as it decodes to it
+			{Key: "_id", Value: bson.M{"$ifNull": bson.A{"$" + field, ""}}},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+			{Key: "average_score", Value: bson.M{"$avg": "$score"}},
+			{Key: "positive", Value: countIf(bson.M{"$eq": bson.A{"$sentiment", "positive"}})},
+			{Key: "negative", Value: countIf(bson.M{"$eq": bson.A{"$sentiment", "negative"}})},
+			{Key: "neutral", Value: countIf(bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$sentiment", bson.A{"positive", "negative"}}}}})},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate posts in MongoDB: %v", err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	groups := []PostGroup{}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode post groups from cursor: %v", err)
+	}
+	return groups, nil
+}
+
+// SetCommentSentiment stores the aggregate comment sentiment on the post.
+func (r *MongoPostRepository) SetCommentSentiment(ctx context.Context, key string, sentiment *models.CommentSentiment) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"comment_sentiment": sentiment}})
+	if err != nil {
+		return fmt.Errorf("failed to update comment sentiment of post %s: %v", key, err)
+	}
+	return nil
+}
+
+// Inactive retrieves the posts last stored before the given time, projected on their identity.
+func (r *MongoPostRepository) Inactive(ctx context.Context, before time.Time) ([]models.RedditPost, error) {
+	findOptions := options.Find().
+		SetProjection(bson.M{"_id": 1, "id": 1, "source": 1, "inserted_at": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"inserted_at": bson.M{"$lt": before}}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve inactive posts from MongoDB: %v", err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	posts := []models.RedditPost{}
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, fmt.Errorf("failed to decode posts from cursor: %v", err)
+	}
+	return posts, nil
+}
+
+// Delete deletes the posts with a single DeleteMany.
+func (r *MongoPostRepository) Delete(ctx context.Context, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	res, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete posts from MongoDB: %v", err)
+	}
+	return int(res.DeletedCount), nil
+}
+
+// filterDocument converts a PostFilter into a MongoDB query document.
+func filterDocument(filter PostFilter) bson.M {
+	document := bson.M{}
+
+	// Exact matches on string fields
+	for field, value := range map[string]string{
+		"source":          filter.Source,
+		"sentiment":       filter.Sentiment,
+		"subreddit":       filter.Subreddit,
+		"author":          filter.Author,
+		"link_flair_text": filter.Flair,
+		"domain":          filter.Domain,
+	} {
+		if value != "" {
+			document[field] = value
+		}
+	}
+
+	// Boolean flags
+	for field, value := range map[string]*bool{
+		"over_18":  filter.Over18,
+		"spoiler":  filter.Spoiler,
+		"stickied": filter.Stickied,
+		"is_self":  filter.IsSelf,
+	} {
+		if value != nil {
+			document[field] = *value
+		}
+	}
+
+	// Lower bounds on numeric fields
+	for field, value := range map[string]*int{
+		"score":        filter.MinScore,
+		"num_comments": filter.MinComments,
+	} {
+		if value != nil {
+			document[field] = bson.M{"$gte": *value}
+		}
+	}
+
+	return document
+}
+
+// closeCursor closes a cursor after usage, logging a failure.
+func closeCursor(ctx context.Context, cursor *mongo.Cursor) {
+	if err := cursor.Close(ctx); err != nil {
+		log.Printf("Failed to close cursor: %v", err)
+	}
+}
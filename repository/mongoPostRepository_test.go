@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDatabase connects to the MongoDB server at MONGO_URI and returns a database of its own, dropped when the
+// test ends. The test is skipped when MONGO_URI is unset.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("failed to ping MongoDB: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("trendlens_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Drop(ctx); err != nil {
+			t.Errorf("failed to drop %s: %v", db.Name(), err)
+		}
+		_ = client.Disconnect(ctx)
+	})
+	return db
+}
+
+func TestMongoPostRepository(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	collection := db.Collection("posts")
+	if err := EnsureIndexes(ctx, collection, PostIndexes, DriftFail); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPostRepository(ctx, NewMongoPostRepository(collection)); err != nil {
+		t.Fatal(err)
+	}
+}
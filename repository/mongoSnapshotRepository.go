@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// namespaceExists is the code of the error MongoDB returns when creating a collection that already exists.
+const namespaceExists = 48
+
+// rollupBatchSize is the number of rolled-up points written per BulkWrite.
+const rollupBatchSize = 1000
+
+// MongoSnapshotRepository is the SnapshotRepository storing raw snapshots in a MongoDB time-series collection and
+// rolled-up points in regular hourly and daily collections.
+//
+// Compact deletes snapshots by observation time, which time-series collections only support since MongoDB 7.0.
+type MongoSnapshotRepository struct {
+	raw    *mongo.Collection // The time-series collection where snapshots are recorded
+	hourly *mongo.Collection // The collection of hourly points
+	daily  *mongo.Collection // The collection of daily points
+}
+
+// NewMongoSnapshotRepository creates a MongoSnapshotRepository storing snapshots in the given collections,
+// which should have been created by EnsureSnapshotCollection and EnsureRollupCollection.
+func NewMongoSnapshotRepository(raw *mongo.Collection, hourly *mongo.Collection, daily *mongo.Collection) *MongoSnapshotRepository {
+	return &MongoSnapshotRepository{raw: raw, hourly: hourly, daily: daily}
+}
+
+// EnsureSnapshotCollection creates the time-series collection for vote snapshots if it does not exist yet,
+// with the post key as metaField and the observation time as timeField, and indexes it for per-post series.
+// An existing collection is left as is, since the time-series options of a collection cannot be changed.
+func EnsureSnapshotCollection(ctx context.Context, db *mongo.Database, name string) (*mongo.Collection, error) {
+	timeSeries := options.TimeSeries().SetTimeField("observed_at").SetMetaField("post_id").SetGranularity("minutes")
+	err := db.CreateCollection(ctx, name, options.CreateCollection().SetTimeSeriesOptions(timeSeries))
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && commandErr.Code == namespaceExists) {
+		return nil, fmt.Errorf("failed to create time-series collection %s: %v", name, err)
+	}
+
+	// Recent MongoDB versions create this index themselves; creating it again with the same keys is a no-op
+	collection := db.Collection(name)
+	model := mongo.IndexModel{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "observed_at", Value: 1}}}
+	if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+		return nil, fmt.Errorf("failed to index time-series collection %s: %v", name, err)
+	}
+	return collection, nil
+}
+
+// EnsureRollupCollection indexes a collection of rolled-up points for per-post series and for compaction.
+func EnsureRollupCollection(ctx context.Context, db *mongo.Database, name string) (*mongo.Collection, error) {
+	collection := db.Collection(name)
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "time", Value: 1}}},
+		{Keys: bson.D{{Key: "time", Value: 1}}},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return nil, fmt.Errorf("failed to index rollup collection %s: %v", name, err)
+	}
+	return collection, nil
+}
+
+// Record inserts the snapshots with a single unordered InsertMany.
+func (r *MongoSnapshotRepository) Record(ctx context.Context, snapshots []models.VoteSnapshot) error {
+	documents := make([]any, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.PostKey != "" {
+			documents = append(documents, snapshot)
+		}
+	}
+	if len(documents) == 0 {
+		return nil
+	}
+
+	if _, err := r.raw.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to insert vote snapshots into MongoDB: %v", err)
+	}
+	return nil
+}
+
+// Series summarizes the raw snapshots of a post with an aggregation pipeline and combines them with its
+// rolled-up points.
+func (r *MongoSnapshotRepository) Series(ctx context.Context, postKey string, query SeriesQuery) ([]models.SeriesPoint, error) {
+	// Raw series group the snapshots by time; other resolutions by the start of their interval
+	var interval any = "$observed_at"
+	if query.Resolution > 0 {
+		interval = intervalExpression("$observed_at", query.Resolution)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: seriesFilter(postKey, "observed_at", query)}},
+		{{Key: "$sort", Value: bson.D{{Key: "observed_at", Value: 1}}}},
+	}
+	pipeline = append(pipeline, summaryStages(interval)...)
+	pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.M{"time": "$_id"}}})
+
+	cursor, err := r.raw.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate vote snapshots of post %s in MongoDB: %v", postKey, err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	var points []models.SeriesPoint
+	if err = cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode series points from cursor: %v", err)
+	}
+
+	// Add the rolled-up points of the range
+	for _, collection := range []*mongo.Collection{r.hourly, r.daily} {
+		cursor, err := collection.Find(ctx, seriesFilter(postKey, "time", query))
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve rolled-up points of post %s from %s: %v", postKey, collection.Name(), err)
+		}
+		var rollups []models.SeriesPoint
+		err = cursor.All(ctx, &rollups)
+		closeCursor(ctx, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode series points from cursor: %v", err)
+		}
+		points = append(points, rollups...)
+	}
+
+	return combinePoints(points, query.Resolution), nil
+}
+
+// Compact rolls the old raw snapshots up into hourly points and the old hourly points up into daily points.
+// Every tier is rolled up with an aggregation pipeline, its points are written with unordered BulkWrites and
+// the rolled-up documents are deleted with a single DeleteMany.
+func (r *MongoSnapshotRepository) Compact(ctx context.Context, cutoffs CompactionCutoffs, dryRun bool) (*CompactionResult, error) {
+	result := &CompactionResult{}
+
+	var err error
+	result.RawCompacted, result.HourlyWritten, err = rollUp(ctx, r.raw, "observed_at", cutoffs.Raw, time.Hour, r.hourly, dryRun)
+	if err != nil {
+		return result, err
+	}
+	result.HourlyCompacted, result.DailyWritten, err = rollUp(ctx, r.hourly, "time", cutoffs.Hourly, 24*time.Hour, r.daily, dryRun)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// rolledPoint is a point computed by the pipeline of rollUp.
+type rolledPoint struct {
+	ID struct {
+		PostKey string    `bson:"post_id"` // Key of the post
+		Time    time.Time `bson:"time"`    // Start of the interval
+	} `bson:"_id"`
+	Documents          int              `bson:"documents"` // Number of documents rolled up into the point
+	models.SeriesPoint `bson:",inline"` // Summary of the interval
+}
+
+// rollUp summarizes the documents of source whose timeField is before cutoff into points of the given resolution,
+// writes them into target and deletes the summarized documents. Raw snapshots and rolled-up points are both
+// accepted as input, since a snapshot summarizes like a point of one sample.
+// It returns the number of documents rolled up and the number of points written.
+func rollUp(ctx context.Context, source *mongo.Collection, timeField string, cutoff time.Time, resolution time.Duration, target *mongo.Collection, dryRun bool) (int, int, error) {
+	filter := bson.M{timeField: bson.M{"$lt": cutoff}}
+	interval := bson.M{"post_id": "$post_id", "time": intervalExpression("$"+timeField, resolution)}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: timeField, Value: 1}}}},
+	}
+	pipeline = append(pipeline, summaryStages(interval)...)
+
+	cursor, err := source.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to roll up %s: %v", source.Name(), err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	compacted, written := 0, 0
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 || dryRun {
+			writes = writes[:0]
+			return nil
+		}
+		_, err := target.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		if err != nil {
+			return fmt.Errorf("failed to write rolled-up points into %s: %v", target.Name(), err)
+		}
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var point rolledPoint
+		if err := cursor.Decode(&point); err != nil {
+			return compacted, written, fmt.Errorf("failed to decode rolled-up point from cursor: %v", err)
+		}
+		point.Time = point.ID.Time.UTC()
+		rollup := models.VoteRollup{ID: rollupID(point.ID.PostKey, point.Time), PostKey: point.ID.PostKey, SeriesPoint: point.SeriesPoint}
+		writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": rollup.ID}).SetReplacement(rollup).SetUpsert(true))
+		compacted += point.Documents
+		written++
+
+		if len(writes) == rollupBatchSize {
+			if err := flush(); err != nil {
+				return compacted, written, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return compacted, written, fmt.Errorf("failed to read rolled-up points of %s: %v", source.Name(), err)
+	}
+	if err := flush(); err != nil {
+		return compacted, written, err
+	}
+
+	// Only delete what was rolled up once every point is written
+	if !dryRun && written > 0 {
+		if _, err := source.DeleteMany(ctx, filter); err != nil {
+			return compacted, written, fmt.Errorf("failed to delete rolled-up documents from %s: %v", source.Name(), err)
+		}
+	}
+	return compacted, written, nil
+}
+
+// Delete deletes the snapshots and points of the posts from every collection.
+func (r *MongoSnapshotRepository) Delete(ctx context.Context, postKeys []string, dryRun bool) (int, error) {
+	if len(postKeys) == 0 {
+		return 0, nil
+	}
+
+	filter := bson.M{"post_id": bson.M{"$in": postKeys}}
+	deleted := 0
+	for _, collection := range []*mongo.Collection{r.raw, r.hourly, r.daily} {
+		if dryRun {
+			count, err := collection.CountDocuments(ctx, filter)
+			if err != nil {
+				return deleted, fmt.Errorf("failed to count vote history in %s: %v", collection.Name(), err)
+			}
+			deleted += int(count)
+			continue
+		}
+		res, err := collection.DeleteMany(ctx, filter)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete vote history from %s: %v", collection.Name(), err)
+		}
+		deleted += int(res.DeletedCount)
+	}
+	return deleted, nil
+}
+
+// seriesFilter selects the documents of a post whose timeField is in the query's range.
+func seriesFilter(postKey string, timeField string, query SeriesQuery) bson.M {
+	filter := bson.M{"post_id": postKey}
+	bounds := bson.M{}
+	if !query.From.IsZero() {
+		bounds["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		bounds["$lt"] = query.To
+	}
+	if len(bounds) > 0 {
+		filter[timeField] = bounds
+	}
+	return filter
+}
+
+// intervalExpression returns the expression of the start of the epoch-aligned interval a date field falls in.
+func intervalExpression(field string, resolution time.Duration) bson.M {
+	return bson.M{"$subtract": bson.A{field, bson.M{"$mod": bson.A{bson.M{"$toLong": field}, resolution.Milliseconds()}}}}
+}
+
+// summaryStages return the stages summarizing sorted snapshots or points into the groups identified by id, like
+// combinePoints. Snapshots have no samples or score statistics and count as a point of one sample.
+func summaryStages(id any) []bson.D {
+	samples := bson.M{"$ifNull": bson.A{"$samples", 1}}
+	return []bson.D{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "documents", Value: bson.M{"$sum": 1}},
+			{Key: "samples", Value: bson.M{"$sum": samples}},
+			{Key: "score", Value: bson.M{"$last": "$score"}},
+			{Key: "score_min", Value: bson.M{"$min": bson.M{"$ifNull": bson.A{"$score_min", "$score"}}}},
+			{Key: "score_max", Value: bson.M{"$max": bson.M{"$ifNull": bson.A{"$score_max", "$score"}}}},
+			{Key: "score_total", Value: bson.M{"$sum": bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$score_avg", "$score"}}, samples}}}},
+			{Key: "ups", Value: bson.M{"$last": "$ups"}},
+			{Key: "comments", Value: bson.M{"$last": "$comments"}},
+			{Key: "rank", Value: bson.M{"$last": "$rank"}},
+			{Key: "upvote_ratio", Value: bson.M{"$last": "$upvote_ratio"}},
+		}}},
+		// Weigh the average scores of the summarized points by their samples
+		{{Key: "$set", Value: bson.M{"score_avg": bson.M{"$divide": bson.A{"$score_total", "$samples"}}}}},
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMongoSnapshotRepository(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	raw, err := EnsureSnapshotCollection(ctx, db, "vote_snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hourly, err := EnsureRollupCollection(ctx, db, "vote_snapshots_hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := EnsureRollupCollection(ctx, db, "vote_snapshots_daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSnapshotRepository(ctx, NewMongoSnapshotRepository(raw, hourly, daily)); err != nil {
+		t.Fatal(err)
+	}
+}
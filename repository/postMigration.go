@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"slices"
+)
+
+// KeyMigrationResult reports the outcome of MigratePostKeys.
+type KeyMigrationResult struct {
+	Documents int     // Number of legacy documents found
+	Posts     int     // Number of posts the legacy documents were merged into
+	Merged    int     // Number of legacy documents that duplicated another document of the same post
+	Failed    int     // Number of posts that could not be migrated
+	Errors    []error // Errors of the failed posts
+}
+
+// legacyDocument is a post stored before posts were keyed by models.PostKey, with its original _id.
+type legacyDocument struct {
+	ID   any               // Original _id, usually an ObjectID
+	Post models.RedditPost // The decoded post
+}
+
+// MigratePostKeys rewrites the posts of the collection stored before posts were keyed by models.PostKey.
+//
+// Legacy documents have a generated _id (an ObjectID) and were upserted by their platform ID, so concurrent
+// upserts could store the same post more than once. Every post is rewritten as a single document keyed by
+// models.PostKey, merged with its duplicates and with any document already stored under the new key:
+// the fields of the most recently inserted document win, the vote histories are combined in time order without
+// repeated entries, and the most recent comment sentiment is kept. Documents without a source get defaultSource.
+//
+// Every merged document is written before its legacy documents are deleted, so the migration is idempotent and
+// can be interrupted: an interrupted post is merged again with its remaining legacy documents by the next run.
+// With dryRun set it only reports what it would do.
+func MigratePostKeys(ctx context.Context, collection *mongo.Collection, defaultSource string, dryRun bool) (*KeyMigrationResult, error) {
+	// Legacy documents are the ones whose _id is not a string; sorting by platform ID makes duplicates adjacent
+	filter := bson.M{"_id": bson.M{"$not": bson.M{"$type": "string"}}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "id", Value: 1}, {Key: "inserted_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find legacy posts in %s: %v", collection.Name(), err)
+	}
+	defer closeCursor(ctx, cursor)
+
+	result := &KeyMigrationResult{}
+	var group []legacyDocument // Documents sharing the platform ID being read
+
+	// flush migrates the documents of the current group, which may hold several posts of different sources
+	flush := func() {
+		byKey := make(map[string][]legacyDocument)
+		var keys []string
+		for _, document := range group {
+			key := models.PostKey(document.Post.Source, document.Post.PostID)
+			if _, ok := byKey[key]; !ok {
+				keys = append(keys, key)
+			}
+			byKey[key] = append(byKey[key], document)
+		}
+		for _, key := range keys {
+			result.Posts++
+			result.Merged += len(byKey[key]) - 1
+			if err := migratePost(ctx, collection, key, byKey[key], dryRun); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, err)
+			}
+		}
+		group = group[:0]
+	}
+
+	for cursor.Next(ctx) {
+		id := cursor.Current.Lookup("_id")
+		var document legacyDocument
+		if err := id.Unmarshal(&document.ID); err != nil {
+			return result, fmt.Errorf("failed to decode _id of a legacy post in %s: %v", collection.Name(), err)
+		}
+		if err := bson.Unmarshal(cursor.Current, &document.Post); err != nil {
+			return result, fmt.Errorf("failed to decode legacy post %v in %s: %v", id, collection.Name(), err)
+		}
+		result.Documents++
+
+		if document.Post.PostID == "" {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("legacy post %v has no platform ID", id))
+			continue
+		}
+		if document.Post.Source == "" {
+			document.Post.Source = defaultSource
+		}
+
+		if len(group) > 0 && group[0].Post.PostID != document.Post.PostID {
+			flush()
+		}
+		group = append(group, document)
+	}
+	if err := cursor.Err(); err != nil {
+		return result, fmt.Errorf("failed to read legacy posts from %s: %v", collection.Name(), err)
+	}
+	flush()
+
+	return result, nil
+}
+
+// migratePost replaces the legacy documents of a post with a single document stored under its key.
+//
+// The merged document is written before the legacy documents are deleted, so that the post is never missing;
+// if the deletion fails, the next run merges the remaining legacy documents into it again.
+func migratePost(ctx context.Context, collection *mongo.Collection, key string, documents []legacyDocument, dryRun bool) error {
+	// Merge with the document already stored under the key, if a new-style upsert created one
+	posts := make([]models.RedditPost, 0, len(documents)+1)
+	var current models.RedditPost
+	err := collection.FindOne(ctx, bson.M{"_id": key}).Decode(&current)
+	switch {
+	case err == nil:
+		posts = append(posts, current)
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return fmt.Errorf("failed to retrieve post %s from %s: %v", key, collection.Name(), err)
+	}
+
+	ids := make([]any, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+		posts = append(posts, document.Post)
+	}
+
+	merged := mergePosts(posts)
+	merged.ID = key
+	if dryRun {
+		log.Printf("Would merge %d legacy documents of %s into %s", len(documents), collection.Name(), key)
+		return nil
+	}
+
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": key}, merged, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to write post %s to %s: %v", key, collection.Name(), err)
+	}
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to delete legacy documents of post %s from %s: %v", key, collection.Name(), err)
+	}
+	return nil
+}
+
+// mergePosts merges several documents of the same post.
+// The fields of the most recently inserted document win, the vote histories are combined in time order without
+// repeated entries, and the most recently updated comment sentiment is kept.
+func mergePosts(posts []models.RedditPost) models.RedditPost {
+	merged := posts[0]
+	var upvotes, downvotes []models.VoteHistoryEntry
+	var sentiment *models.CommentSentiment
+	for _, post := range posts {
+		if !post.InsertedAt.Before(merged.InsertedAt) {
+			merged = post
+		}
+		upvotes = append(upvotes, post.UpvoteHistory...)
+		downvotes = append(downvotes, post.DownvoteHistory...)
+		if post.CommentSentiment != nil && (sentiment == nil || post.CommentSentiment.UpdatedAt.After(sentiment.UpdatedAt)) {
+			sentiment = post.CommentSentiment
+		}
+	}
+
+	merged.UpvoteHistory = mergeHistory(upvotes)
+	merged.DownvoteHistory = mergeHistory(downvotes)
+	merged.CommentSentiment = sentiment
+	return merged
+}
+
+// mergeHistory sorts vote history entries by time and removes repeated entries.
+// The result is never nil, since $push fails on a history stored as null.
+func mergeHistory(entries []models.VoteHistoryEntry) []models.VoteHistoryEntry {
+	if entries == nil {
+		return []models.VoteHistoryEntry{}
+	}
+	slices.SortStableFunc(entries, func(a, b models.VoteHistoryEntry) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return slices.CompactFunc(entries, func(a, b models.VoteHistoryEntry) bool {
+		return a.Value == b.Value && a.Timestamp.Equal(b.Timestamp)
+	})
+}
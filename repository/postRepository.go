@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// PostRepository stores trending posts. Their votes over time are recorded by a SnapshotRepository.
+//
+// Posts are identified by their key (models.RedditPost.ID, see models.PostKey). Implementations must be safe for
+// concurrent use and must behave identically; CheckPostRepository verifies an implementation against that contract.
+type PostRepository interface {
+	// Upsert inserts the posts that are not stored yet and updates the others. The vote histories, which are no
+	// longer written and only hold the votes recorded before snapshots, and the comment sentiment are never
+	// overwritten.
+	// A post listed more than once is only written once, with its first occurrence.
+	// Posts that cannot be written are counted as failed in the result without stopping the other writes;
+	// the error is only set if the batch could not be written at all.
+	Upsert(ctx context.Context, posts []models.RedditPost) (*UpsertResult, error)
+
+	// Get returns the post with the given key, or nil if there is none.
+	Get(ctx context.Context, key string) (*models.RedditPost, error)
+
+	// Find returns the posts matching the query's filter, sorted and paginated as requested.
+	Find(ctx context.Context, query PostQuery) ([]models.RedditPost, error)
+
+	// Aggregate groups the posts matching the filter by a field and summarizes every group,
+	// largest group first and then by key.
+	Aggregate(ctx context.Context, filter PostFilter, groupBy GroupField) ([]PostGroup, error)
+
+	// SetCommentSentiment stores the aggregate comment sentiment of a post. It does nothing if the post is not stored.
+	SetCommentSentiment(ctx context.Context, key string, sentiment *models.CommentSentiment) error
+
+	// Inactive returns the posts last stored (InsertedAt) before the given time, ordered by key.
+	// Only their ID, PostID, Source and InsertedAt fields are set.
+	Inactive(ctx context.Context, before time.Time) ([]models.RedditPost, error)
+
+	// Delete deletes the posts with the given keys and returns how many were stored.
+	Delete(ctx context.Context, keys []string) (int, error)
+}
+
+// UpsertResult reports the outcome of an upsert.
+type UpsertResult struct {
+	Inserted int     // Number of posts that were not stored yet
+	Modified int     // Number of stored posts that changed
+	Failed   int     // Number of posts whose write failed
+	Errors   []error // Errors of the failed writes
+}
+
+// PostFilter selects posts. Zero-valued fields do not filter.
+type PostFilter struct {
+	Source      string // Platform the post was fetched from
+	Sentiment   string // Sentiment of the title
+	Subreddit   string // Subreddit of the post
+	Author      string // Author of the post
+	Flair       string // Flair text of the post
+	Domain      string // Domain of the outbound URL
+	Over18      *bool  // Whether the post is marked NSFW
+	Spoiler     *bool  // Whether the post is marked as a spoiler
+	Stickied    *bool  // Whether the post is stickied
+	IsSelf      *bool  // Whether the post is a text post
+	MinScore    *int   // Lower bound on the score
+	MinComments *int   // Lower bound on the number of comments
+}
+
+// SortField is a field posts can be sorted by.
+type SortField string
+
+const (
+	SortRank     SortField = "rank"     // Position in the listing when last observed
+	SortScore    SortField = "score"    // Net score
+	SortUpvotes  SortField = "upvotes"  // Number of upvotes
+	SortComments SortField = "comments" // Number of comments
+	SortCreated  SortField = "created"  // Creation time on the platform
+	SortInserted SortField = "inserted" // Time the post was last stored
+)
+
+// sortFields maps every sort field to the document field it sorts by.
+var sortFields = map[SortField]string{
+	SortRank:     "rank",
+	SortScore:    "score",
+	SortUpvotes:  "upvotes",
+	SortComments: "num_comments",
+	SortCreated:  "created_utc",
+	SortInserted: "inserted_at",
+}
+
+// ParseSortField converts a query parameter value into a SortField.
+func ParseSortField(value string) (SortField, error) {
+	field := SortField(value)
+	if _, ok := sortFields[field]; !ok {
+		return "", fmt.Errorf("invalid sort field %q", value)
+	}
+	return field, nil
+}
+
+// PostQuery selects, sorts and paginates posts.
+//
+// Posts with equal sort values, and all posts when Sort is empty, are ordered by key,
+// so that pagination is stable.
+type PostQuery struct {
+	Filter     PostFilter // Posts to select
+	Sort       SortField  // Field to sort by, empty to sort by key only
+	Descending bool       // Whether to sort in descending order
+	Skip       int        // Number of posts to skip
+	Limit      int        // Maximum number of posts to return, zero for no limit
+}
+
+// GroupField is a field posts can be grouped by.
+type GroupField string
+
+const (
+	GroupSource    GroupField = "source"    // Platform the post was fetched from
+	GroupSentiment GroupField = "sentiment" // Sentiment of the title
+	GroupSubreddit GroupField = "subreddit" // Subreddit of the post
+	GroupCommunity GroupField = "community" // Community of the post on other platforms
+	GroupDomain    GroupField = "domain"    // Domain of the outbound URL
+	GroupAuthor    GroupField = "author"    // Author of the post
+)
+
+// groupFields maps every group field to the document field it groups by.
+var groupFields = map[GroupField]string{
+	GroupSource:    "source",
+	GroupSentiment: "sentiment",
+	GroupSubreddit: "subreddit",
+	GroupCommunity: "community",
+	GroupDomain:    "domain",
+	GroupAuthor:    "author",
+}
+
+// ParseGroupField converts a query parameter value into a GroupField.
+func ParseGroupField(value string) (GroupField, error) {
+	field := GroupField(value)
+	if _, ok := groupFields[field]; !ok {
+		return "", fmt.Errorf("invalid group field %q", value)
+	}
+	return field, nil
+}
+
+// PostGroup summarizes the posts sharing a value of the grouped field.
+type PostGroup struct {
+	Key          string  `bson:"_id" json:"key"`                     // Value of the grouped field
+	Count        int     `bson:"count" json:"count"`                 // Number of posts in the group
+	AverageScore float64 `bson:"average_score" json:"average_score"` // Average score of the posts
+	Positive     int     `bson:"positive" json:"positive"`           // Number of posts with a positive title
+	Negative     int     `bson:"negative" json:"negative"`           // Number of posts with a negative title
+	Neutral      int     `bson:"neutral" json:"neutral"`             // Number of posts with a neutral title
+}
+
+// uniquePosts returns the first occurrence of every post, in order, and an error for every post without a key.
+func uniquePosts(posts []models.RedditPost) ([]models.RedditPost, []error) {
+	seen := make(map[string]bool, len(posts))
+	var unique []models.RedditPost
+	var invalid []error
+	for i, post := range posts {
+		if post.ID == "" {
+			invalid = append(invalid, fmt.Errorf("post at index %d has no key", i))
+			continue
+		}
+		if seen[post.ID] {
+			continue
+		}
+		seen[post.ID] = true
+		unique = append(unique, post)
+	}
+	return unique, invalid
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CheckSnapshotRepository runs the conformance suite of the SnapshotRepository contract against an empty
+// repository and returns an error describing the first violation, like CheckPostRepository.
+//
+// The suite writes snapshots whose post keys start with "conformance-"; the repository must not hold any others.
+func CheckSnapshotRepository(ctx context.Context, repo SnapshotRepository) error {
+	// Two posts observed every 20 minutes for two hours, recorded out of order, plus a snapshot without a key
+	var snapshots []models.VoteSnapshot
+	for i := 5; i >= 0; i-- {
+		for _, key := range []string{"conformance-a", "conformance-b"} {
+			snapshots = append(snapshots, models.VoteSnapshot{
+				PostKey:     key,
+				ObservedAt:  conformanceStart.Add(time.Duration(i) * 20 * time.Minute),
+				Score:       10 * i,
+				Upvotes:     12 * i,
+				NumComments: i,
+				Rank:        10 - i,
+				UpvoteRatio: 0.5,
+			})
+		}
+	}
+	snapshots = append(snapshots, models.VoteSnapshot{ObservedAt: conformanceStart, Score: 1})
+	if err := repo.Record(ctx, snapshots); err != nil {
+		return fmt.Errorf("Record: %v", err)
+	}
+
+	// Raw series return every snapshot, oldest first
+	points, err := repo.Series(ctx, "conformance-a", SeriesQuery{})
+	if err != nil {
+		return fmt.Errorf("raw: Series: %v", err)
+	}
+	if len(points) != 6 || !points[0].Time.Equal(conformanceStart) || points[5].Score != 50 || points[5].Samples != 1 {
+		return fmt.Errorf("raw: Series returned %+v, want 6 snapshots from %v", points, conformanceStart)
+	}
+
+	// The range includes From and excludes To
+	points, err = repo.Series(ctx, "conformance-a", SeriesQuery{From: conformanceStart.Add(20 * time.Minute), To: conformanceStart.Add(time.Hour)})
+	if err != nil {
+		return fmt.Errorf("range: Series: %v", err)
+	}
+	if len(points) != 2 || points[0].Score != 10 || points[1].Score != 20 {
+		return fmt.Errorf("range: Series returned %+v, want the scores 10 and 20", points)
+	}
+
+	// Hourly points summarize three snapshots each and keep the last values
+	want := []models.SeriesPoint{
+		{Time: conformanceStart, Samples: 3, Score: 20, ScoreMin: 0, ScoreMax: 20, ScoreAvg: 10, Upvotes: 24, NumComments: 2, Rank: 8, UpvoteRatio: 0.5},
+		{Time: conformanceStart.Add(time.Hour), Samples: 3, Score: 50, ScoreMin: 30, ScoreMax: 50, ScoreAvg: 40, Upvotes: 60, NumComments: 5, Rank: 5, UpvoteRatio: 0.5},
+	}
+	if err := compareSeries(ctx, repo, "hourly", SeriesQuery{Resolution: time.Hour}, want); err != nil {
+		return err
+	}
+
+	// A dry run reports the compaction without changing the series
+	day := conformanceStart.Truncate(24 * time.Hour)
+	cutoffs := CompactionCutoffs{Raw: conformanceStart.Add(time.Hour), Hourly: day}
+	result, err := repo.Compact(ctx, cutoffs, true)
+	if err != nil {
+		return fmt.Errorf("dry run: Compact: %v", err)
+	}
+	if *result != (CompactionResult{RawCompacted: 6, HourlyWritten: 2}) {
+		return fmt.Errorf("dry run: Compact returned %+v, want 6 raw snapshots into 2 hourly points", result)
+	}
+	if points, err := repo.Series(ctx, "conformance-a", SeriesQuery{}); err != nil || len(points) != 6 {
+		return fmt.Errorf("dry run: Series returned %d points (%v), want 6", len(points), err)
+	}
+
+	// The first hour of both posts is rolled up; the series at hourly resolution is unchanged
+	result, err = repo.Compact(ctx, cutoffs, false)
+	if err != nil {
+		return fmt.Errorf("compact: Compact: %v", err)
+	}
+	if *result != (CompactionResult{RawCompacted: 6, HourlyWritten: 2}) {
+		return fmt.Errorf("compact: Compact returned %+v, want 6 raw snapshots into 2 hourly points", result)
+	}
+	if err := compareSeries(ctx, repo, "compact", SeriesQuery{Resolution: time.Hour}, want); err != nil {
+		return err
+	}
+
+	// Raw series return the hourly point of the first hour, then the remaining snapshots
+	points, err = repo.Series(ctx, "conformance-a", SeriesQuery{})
+	if err != nil {
+		return fmt.Errorf("compact: Series: %v", err)
+	}
+	if len(points) != 4 || points[0].Samples != 3 || points[1].Samples != 1 || !points[1].Time.Equal(conformanceStart.Add(time.Hour)) {
+		return fmt.Errorf("compact: raw Series returned %+v, want an hourly point and 3 snapshots", points)
+	}
+
+	// Rolling up both hours into a day keeps the daily summary
+	result, err = repo.Compact(ctx, CompactionCutoffs{Raw: conformanceStart.Add(2 * time.Hour), Hourly: day.Add(24 * time.Hour)}, false)
+	if err != nil {
+		return fmt.Errorf("daily: Compact: %v", err)
+	}
+	if *result != (CompactionResult{RawCompacted: 6, HourlyWritten: 2, HourlyCompacted: 4, DailyWritten: 2}) {
+		return fmt.Errorf("daily: Compact returned %+v, want 6 raw snapshots into 2 hourly points and 4 hourly points into 2 daily points", result)
+	}
+	daily := []models.SeriesPoint{
+		{Time: day, Samples: 6, Score: 50, ScoreMin: 0, ScoreMax: 50, ScoreAvg: 25, Upvotes: 60, NumComments: 5, Rank: 5, UpvoteRatio: 0.5},
+	}
+	if err := compareSeries(ctx, repo, "daily", SeriesQuery{Resolution: time.Hour}, daily); err != nil {
+		return err
+	}
+
+	// Deleting a post deletes all its snapshots and points
+	if deleted, err := repo.Delete(ctx, []string{"conformance-b", "conformance-missing"}, true); err != nil || deleted != 1 {
+		return fmt.Errorf("Delete in dry run returned %d (%v), want 1", deleted, err)
+	}
+	if deleted, err := repo.Delete(ctx, []string{"conformance-b", "conformance-missing"}, false); err != nil || deleted != 1 {
+		return fmt.Errorf("Delete returned %d (%v), want 1", deleted, err)
+	}
+	if points, err := repo.Series(ctx, "conformance-b", SeriesQuery{}); err != nil || len(points) != 0 {
+		return fmt.Errorf("Series of a deleted post returned %+v (%v)", points, err)
+	}
+
+	// A post without snapshots has an empty series
+	points, err = repo.Series(ctx, "conformance-missing", SeriesQuery{})
+	if err != nil {
+		return fmt.Errorf("missing: Series: %v", err)
+	}
+	if points == nil || len(points) != 0 {
+		return fmt.Errorf("missing: Series returned %+v, want an empty slice", points)
+	}
+	return nil
+}
+
+// compareSeries reports the first difference between the series of post a and the expected points,
+// comparing averages with a tolerance.
+func compareSeries(ctx context.Context, repo SnapshotRepository, name string, query SeriesQuery, want []models.SeriesPoint) error {
+	points, err := repo.Series(ctx, "conformance-a", query)
+	if err != nil {
+		return fmt.Errorf("%s: Series: %v", name, err)
+	}
+	if len(points) != len(want) {
+		return fmt.Errorf("%s: Series returned %+v, want %+v", name, points, want)
+	}
+	for i := range want {
+		got := points[i]
+		if !got.Time.Equal(want[i].Time) || got.Time.Location() != time.UTC {
+			return fmt.Errorf("%s: Series returned time %v at index %d, want %v in UTC", name, got.Time, i, want[i].Time)
+		}
+		got.Time = want[i].Time
+		got.ScoreAvg = math.Round(got.ScoreAvg*1e9) / 1e9
+		if got != want[i] {
+			return fmt.Errorf("%s: Series returned %+v at index %d, want %+v", name, got, i, want[i])
+		}
+	}
+	return nil
+}
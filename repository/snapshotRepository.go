@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// SnapshotRepository stores the vote snapshots taken by every scrape and serves them as time series.
+//
+// Snapshots age through three tiers: raw snapshots, hourly points and daily points. Compact rolls the older
+// snapshots of a tier up into the next one, so old series are only available at a coarser resolution.
+//
+// Snapshots are identified by their post key and observation time. Implementations must be safe for concurrent
+// use and must behave identically; CheckSnapshotRepository verifies an implementation against that contract.
+type SnapshotRepository interface {
+	// Record stores the snapshots. Snapshots without a post key are skipped.
+	Record(ctx context.Context, snapshots []models.VoteSnapshot) error
+
+	// Series returns the snapshots of a post within the query's time range, oldest first, summarized at the
+	// query's resolution. Rolled-up points are included at their own resolution when it is coarser than the
+	// query's; a rolled-up point is in the range if its interval starts in it.
+	// It returns an empty series for a post without snapshots.
+	Series(ctx context.Context, postKey string, query SeriesQuery) ([]models.SeriesPoint, error)
+
+	// Compact rolls the raw snapshots observed before cutoffs.Raw up into hourly points and the hourly points before
+	// cutoffs.Hourly up into daily points, and deletes what it rolled up. Cutoffs must be aligned on whole hours
+	// and days respectively, so that no interval is split. Rolling up an interval again replaces its point.
+	// With dryRun set nothing is written and the result reports what would be done.
+	Compact(ctx context.Context, cutoffs CompactionCutoffs, dryRun bool) (*CompactionResult, error)
+
+	// Delete deletes the snapshots and points of the given posts and returns how many there were.
+	// With dryRun set nothing is deleted.
+	Delete(ctx context.Context, postKeys []string, dryRun bool) (int, error)
+}
+
+// CompactionCutoffs selects the snapshots Compact rolls up.
+type CompactionCutoffs struct {
+	Raw    time.Time // Raw snapshots observed before this time are rolled up into hourly points
+	Hourly time.Time // Hourly points starting before this time are rolled up into daily points
+}
+
+// CompactionResult reports the outcome of a compaction.
+type CompactionResult struct {
+	RawCompacted    int // Number of raw snapshots rolled up and deleted
+	HourlyWritten   int // Number of hourly points written
+	HourlyCompacted int // Number of hourly points rolled up and deleted
+	DailyWritten    int // Number of daily points written
+}
+
+// SeriesQuery selects and summarizes the snapshots of a post.
+type SeriesQuery struct {
+	From       time.Time     // Earliest observation time to include, zero for no lower bound
+	To         time.Time     // Observation time to stop before, zero for no upper bound
+	Resolution time.Duration // Length of the intervals snapshots are summarized over, zero for every snapshot
+}
+
+// ParseResolution converts a query parameter value into a series resolution: "raw" (or an empty value) for every
+// snapshot, or a duration of at least a second such as "5m", "1h" or "24h". Intervals are aligned on the Unix epoch.
+func ParseResolution(value string) (time.Duration, error) {
+	if value == "" || value == "raw" {
+		return 0, nil
+	}
+	resolution, err := time.ParseDuration(value)
+	if err != nil || resolution < time.Second || resolution%time.Millisecond != 0 {
+		return 0, fmt.Errorf("invalid resolution %q (want raw or a duration of at least 1s)", value)
+	}
+	return resolution, nil
+}
+
+// intervalStart returns the start of the interval of the given resolution an observation time falls in.
+func intervalStart(t time.Time, resolution time.Duration) time.Time {
+	if resolution == 0 {
+		return t
+	}
+	ms := t.UnixMilli()
+	return time.UnixMilli(ms - ms%resolution.Milliseconds()).UTC()
+}
+
+// snapshotPoint returns the raw series point of a single snapshot.
+func snapshotPoint(snapshot models.VoteSnapshot) models.SeriesPoint {
+	return models.SeriesPoint{
+		Time:        snapshot.ObservedAt,
+		Samples:     1,
+		Score:       snapshot.Score,
+		ScoreMin:    snapshot.Score,
+		ScoreMax:    snapshot.Score,
+		ScoreAvg:    float64(snapshot.Score),
+		Upvotes:     snapshot.Upvotes,
+		NumComments: snapshot.NumComments,
+		Rank:        snapshot.Rank,
+		UpvoteRatio: snapshot.UpvoteRatio,
+	}
+}
+
+// combinePoints merges series points into points of the given resolution, oldest first.
+// Merged points add up their samples, keep the extreme scores and the values of the latest point, and weigh
+// their average scores by their samples. Points that are coarser than the resolution keep their own time.
+func combinePoints(points []models.SeriesPoint, resolution time.Duration) []models.SeriesPoint {
+	slices.SortStableFunc(points, func(a, b models.SeriesPoint) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	combined := []models.SeriesPoint{}
+	for _, point := range points {
+		start := intervalStart(point.Time, resolution)
+		if len(combined) == 0 || !combined[len(combined)-1].Time.Equal(start) {
+			point.Time = start
+			combined = append(combined, point)
+			continue
+		}
+
+		last := &combined[len(combined)-1]
+		samples := last.Samples + point.Samples
+		last.ScoreAvg = (last.ScoreAvg*float64(last.Samples) + point.ScoreAvg*float64(point.Samples)) / float64(samples)
+		last.Samples = samples
+		last.ScoreMin = min(last.ScoreMin, point.ScoreMin)
+		last.ScoreMax = max(last.ScoreMax, point.ScoreMax)
+		last.Score = point.Score
+		last.Upvotes = point.Upvotes
+		last.NumComments = point.NumComments
+		last.Rank = point.Rank
+		last.UpvoteRatio = point.UpvoteRatio
+	}
+	return combined
+}
+
+// rollupID returns the _id of the rolled-up point of a post starting at the given time.
+func rollupID(postKey string, start time.Time) string {
+	return postKey + "@" + strconv.FormatInt(start.UnixMilli(), 10)
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// startRun records the start of a run of the named job and returns it.
+// If the run cannot be recorded, the returned run is still usable but has no ID, and the error is returned alongside.
+func startRun(ctx context.Context, collection *mongo.Collection, jobName string) (*models.JobRun, error) {
+	run := &models.JobRun{
+		JobName:   jobName,
+		Status:    models.JobRunRunning,
+		StartedAt: time.Now(),
+	}
+
+	res, err := collection.InsertOne(ctx, run)
+	if err != nil {
+		return run, fmt.Errorf("failed to record job run: %v", err)
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		run.ID = id
+	}
+	return run, nil
+}
+
+// finishRun records the outcome of a run started with startRun.
+func finishRun(collection *mongo.Collection, run *models.JobRun, runErr error) {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Status = models.JobRunSuccess
+	if runErr != nil {
+		run.Status = models.JobRunFailed
+		run.Error = runErr.Error()
+	}
+
+	// The start could not be recorded, so there is nothing to update
+	if run.ID.IsZero() {
+		return
+	}
+
+	// Record the outcome even when the run was cancelled, which is when it matters most
+	_, err := collection.ReplaceOne(context.Background(), bson.M{"_id": run.ID}, run)
+	if err != nil {
+		log.Printf("Failed to record end of job %s: %v", run.JobName, err)
+	}
+}
+
+// ListRuns retrieves the most recent runs of the named job, newest first.
+// It returns a slice of JobRun models or an error if the retrieval fails.
+func ListRuns(ctx context.Context, collection *mongo.Collection, jobName string, limit int64) ([]models.JobRun, error) {
+	findOptions := options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"job_name": jobName}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job runs from MongoDB: %v", err)
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		err := cursor.Close(ctx) // Ensure the cursor is closed after usage
+		if err != nil {
+			log.Printf("Failed to close cursor: %v", err)
+		}
+	}(cursor, ctx)
+
+	runs := []models.JobRun{}
+	if err = cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode job runs from cursor: %v", err)
+	}
+
+	return runs, nil
+}
+
+// getRun retrieves the run of the named job with the given ID, or nil if there is none.
+func getRun(ctx context.Context, collection *mongo.Collection, jobName string, id primitive.ObjectID) (*models.JobRun, error) {
+	var run models.JobRun
+	err := collection.FindOne(ctx, bson.M{"_id": id, "job_name": jobName}).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job run %s: %v", id.Hex(), err)
+	}
+	return &run, nil
+}
+
+// lastRunWithStatus retrieves the most recent run of the named job with the given status, or nil if there is none.
+func lastRunWithStatus(ctx context.Context, collection *mongo.Collection, jobName string, status string) (*models.JobRun, error) {
+	findOptions := options.FindOne().SetSort(bson.M{"started_at": -1})
+
+	var run models.JobRun
+	err := collection.FindOne(ctx, bson.M{"job_name": jobName, "status": status}, findOptions).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve last %s run of job %s: %v", status, jobName, err)
+	}
+	return &run, nil
+}
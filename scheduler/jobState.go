@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// isPaused reports whether the named job is paused. Jobs without a stored state are not paused.
+func isPaused(ctx context.Context, collection *mongo.Collection, jobName string) (bool, error) {
+	var state models.JobState
+	err := collection.FindOne(ctx, bson.M{"_id": jobName}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve state of job %s from MongoDB: %v", jobName, err)
+	}
+	return state.Paused, nil
+}
+
+// pausedJobs retrieves the names of the paused jobs.
+func pausedJobs(ctx context.Context, collection *mongo.Collection) (map[string]bool, error) {
+	cursor, err := collection.Find(ctx, bson.M{"paused": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job states from MongoDB: %v", err)
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		err := cursor.Close(ctx) // Ensure the cursor is closed after usage
+		if err != nil {
+			log.Printf("Failed to close cursor: %v", err)
+		}
+	}(cursor, ctx)
+
+	var states []models.JobState
+	if err = cursor.All(ctx, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode job states from cursor: %v", err)
+	}
+
+	paused := make(map[string]bool, len(states))
+	for _, state := range states {
+		paused[state.Name] = true
+	}
+	return paused, nil
+}
+
+// setPaused stores whether the named job is paused.
+func setPaused(ctx context.Context, collection *mongo.Collection, jobName string, paused bool) error {
+	state := models.JobState{Name: jobName, Paused: paused, UpdatedAt: time.Now()}
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": jobName}, state, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store state of job %s in MongoDB: %v", jobName, err)
+	}
+	return nil
+}
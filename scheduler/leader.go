@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"backend/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lease is a lease-based lock stored in MongoDB that elects a single leader among the replicas of the backend.
+//
+// Every replica runs a Lease with the same name and a unique owner ID. The replica holding the lease renews it
+// with a heartbeat every third of the TTL; the others try to take it over on the same cadence, which only succeeds
+// once the lease has expired. A leader that dies therefore hands over within one TTL. A leader that cannot renew
+// steps down a safety margin of a fifth of the TTL before its lease expires, whether or not a renewal attempt is
+// still pending, and cancels the context of its leadership term so the runs in progress stop too.
+//
+// Expiry times are written with the clock of the replica holding the lease, so the safety margin must comfortably
+// exceed the clock skew between replicas.
+type Lease struct {
+	collection *mongo.Collection  // The collection where leases are stored
+	name       string             // Name of the lease, shared by all replicas
+	owner      string             // Identifier of this replica
+	ttl        time.Duration      // Time a lease stays valid without renewal
+	leader     atomic.Bool        // Whether this replica currently holds the lease, see IsLeader for its validity
+	expiresAt  atomic.Int64       // Expiry of the lease as last written by this replica, in Unix nanoseconds
+	mu         sync.Mutex         // Guards leadership transitions, term and endTerm
+	term       context.Context    // Context of the current leadership term, cancelled when it ends
+	endTerm    context.CancelFunc // Cancels term
+	stop       chan struct{}      // Closed to stop the heartbeat
+	done       chan struct{}      // Closed when the heartbeat has stopped
+	stopOnce   sync.Once          // Guards closing stop
+}
+
+// NewLease creates a lease named name in the given collection, held on behalf of owner.
+//
+// Parameters:
+//   - collection: The MongoDB collection where leases are stored.
+//   - name: The name of the lease, identical on all replicas.
+//   - owner: The identifier of this replica, unique among replicas (see DefaultOwnerID).
+//   - ttl: The time a lease stays valid without renewal.
+//
+// Returns:
+//   - A pointer to the Lease. The lease is not acquired until Start is called.
+func NewLease(collection *mongo.Collection, name string, owner string, ttl time.Duration) *Lease {
+	// Outside a leadership term the term context is cancelled
+	term, endTerm := context.WithCancel(context.Background())
+	endTerm()
+	return &Lease{
+		collection: collection,
+		name:       name,
+		owner:      owner,
+		ttl:        ttl,
+		term:       term,
+		endTerm:    endTerm,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// DefaultOwnerID returns an identifier for this replica made of its hostname, process ID and a random suffix,
+// so that a restarted container never mistakes the lease of its previous incarnation for its own.
+func DefaultOwnerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Start tries to acquire the lease immediately and then runs the heartbeat in the background until Release is called.
+func (l *Lease) Start() {
+	l.tick()
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		// Step down when the lease runs out between heartbeats, e.g. while a renewal is stalled
+		expiry := time.NewTimer(time.Until(l.validUntil()))
+		defer expiry.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.tick()
+				expiry.Reset(time.Until(l.validUntil()))
+			case <-expiry.C:
+				if !l.IsLeader() {
+					l.setLeader(false)
+				}
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+// IsLeader reports whether this replica currently holds the lease and it is valid for longer than the safety margin.
+func (l *Lease) IsLeader() bool {
+	return l.leader.Load() && time.Now().Before(l.validUntil())
+}
+
+// Context returns the context of the current leadership term, which is cancelled when this replica loses or
+// releases the lease. Outside a leadership term, it returns a cancelled context.
+func (l *Lease) Context() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.term
+}
+
+// validUntil returns the time this replica must stop acting as the leader unless it renews the lease.
+func (l *Lease) validUntil() time.Time {
+	return time.Unix(0, l.expiresAt.Load()).Add(-l.ttl / 5)
+}
+
+// setLeader records whether this replica holds the lease, starting or ending the leadership term and logging
+// every transition. It reports whether this replica was the leader before.
+func (l *Lease) setLeader(leader bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	was := l.leader.Swap(leader)
+	if was == leader {
+		return was
+	}
+	if leader {
+		l.term, l.endTerm = context.WithCancel(context.Background())
+		log.Printf("Acquired scheduler lease %s as %s, running scheduled jobs", l.name, l.owner)
+	} else {
+		l.endTerm()
+		log.Printf("Lost scheduler lease %s, no longer running scheduled jobs", l.name)
+	}
+	return was
+}
+
+// Owner returns the identifier of this replica.
+func (l *Lease) Owner() string {
+	return l.owner
+}
+
+// Release stops the heartbeat and gives up the lease if this replica holds it, so another replica
+// can take over without waiting for the lease to expire.
+func (l *Lease) Release() {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	if !l.setLeader(false) {
+		return
+	}
+	filter := bson.M{"_id": l.name, "owner": l.owner}
+	update := bson.M{"$set": bson.M{"expires_at": time.Now()}}
+	if _, err := l.collection.UpdateOne(context.Background(), filter, update); err != nil {
+		log.Printf("Failed to release scheduler lease %s: %v", l.name, err)
+		return
+	}
+	log.Printf("Released scheduler lease %s", l.name)
+}
+
+// tick acquires or renews the lease and updates the leadership state, logging every transition.
+func (l *Lease) tick() {
+	// Bound the attempt so a stalled database cannot delay the heartbeat past the lease's expiry
+	ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+	defer cancel()
+
+	acquired, err := l.tryAcquire(ctx)
+	if err != nil {
+		log.Printf("Failed to renew scheduler lease %s: %v", l.name, err)
+		// Keep leading while the lease we wrote is still valid; nobody else can acquire it before then
+		acquired = l.IsLeader()
+	}
+	l.setLeader(acquired)
+}
+
+// tryAcquire takes the lease if it is free, expired or already owned by this replica, and extends its expiry.
+// It returns false without error if another replica holds a valid lease.
+func (l *Lease) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(l.ttl)
+
+	// Only match a lease this replica may write: its own, or one whose owner stopped renewing it
+	filter := bson.M{
+		"_id": l.name,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+
+	// The update is a pipeline so acquired_at can be kept on renewal and reset on takeover in a single write
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"acquired_at": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$owner", l.owner}}, "$acquired_at", now}},
+			"owner":       l.owner,
+			"renewed_at":  now,
+			"expires_at":  expiresAt,
+		}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var lease models.SchedulerLease
+	err := l.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lease)
+	if mongo.IsDuplicateKeyError(err) {
+		// The filter did not match because another replica holds a valid lease, and the upsert collided with it
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %v", err)
+	}
+
+	l.expiresAt.Store(expiresAt.UnixNano())
+	return true, nil
+}
@@ -0,0 +1,591 @@
+package scheduler
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-co-op/gocron"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Target binds a trend source to the repository and collection its items are stored in.
+type Target struct {
+	Source    services.Source               // The source to fetch items from
+	Posts     repository.PostRepository     // The repository where the fetched posts will be stored
+	Snapshots repository.SnapshotRepository // The repository where vote snapshots will be recorded, nil to skip them
+	Comments  *mongo.Collection             // The collection where comments will be stored, nil to skip comment ingestion
+	Tags      *mongo.Collection             // The collection where trending tags will be stored, nil to skip them
+}
+
+// Task is a scheduled job that does not fetch a source, such as the database maintenance.
+type Task struct {
+	Name     string                                              // Name of the task, looked up as the source of its schedule
+	Run      func(ctx context.Context, run *models.JobRun) error // Performs the task; it may record its outcome on run
+	Schedule config.JobSchedule                                  // Schedule of the task when the configuration has none for it
+}
+
+// Errors returned when a job is triggered manually.
+var (
+	ErrJobNotFound = errors.New("job not found")             // No job has the requested name
+	ErrJobRunning  = errors.New("job is already running")    // A run of the job is in progress
+	ErrStopped     = errors.New("scheduler is stopped")      // The scheduler is shutting down
+	ErrNotLeader   = errors.New("replica is not the leader") // Another replica runs the jobs
+)
+
+// Job is a scheduled fetch of a single target, or a scheduled task.
+//
+// Runs of the same job never overlap: a scheduled or manual run that would start while another one is
+// in progress is skipped (scheduled) or rejected with ErrJobRunning (manual).
+type Job struct {
+	Name     string             // Name of the job, unique within the scheduler
+	Target   Target             // The target fetched by the job, unset for a task
+	Task     *Task              // The task run by the job, nil for a fetch
+	Schedule config.JobSchedule // When the job runs
+	job      *gocron.Job        // The underlying gocron job
+	running  atomic.Bool        // Whether a run is in progress
+}
+
+// Scheduler runs the fetch jobs of all targets.
+// Jobs are grouped into one gocron scheduler per timezone, since a gocron scheduler evaluates all its cron
+// expressions in a single location.
+// Every run is recorded in the job_runs collection.
+//
+// When several replicas share a database, a Lease elects the one replica whose scheduled runs actually execute;
+// the gocron schedulers keep ticking on every replica so a follower can take over as soon as it becomes leader.
+// Runs in progress are only known to the leader, so the operations on jobs return ErrNotLeader on the other
+// replicas. Paused jobs are stored in the job_states collection, so a pause is kept across restarts and leaders.
+type Scheduler struct {
+	schedulers   map[string]*gocron.Scheduler // gocron schedulers keyed by timezone name
+	jobs         []*Job                       // All scheduled jobs, in configuration order
+	runs         *mongo.Collection            // The collection where job runs are recorded
+	states       *mongo.Collection            // The collection where the paused jobs are stored
+	lease        *Lease                       // Leader election lease, nil when this is the only replica
+	commentPosts int                          // Number of top posts whose comments are ingested per run
+	commentLimit int                          // Maximum number of comments ingested per post
+	ctx          context.Context              // Context of all runs, cancelled when a graceful stop times out
+	cancel       context.CancelFunc           // Cancels ctx
+	mu           sync.Mutex                   // Guards stopped and the registration of runs in active
+	stopped      bool                         // Whether Stop has been called
+	active       sync.WaitGroup               // Runs in progress, scheduled and manual
+}
+
+// JobStatus summarizes the state of a job for the jobs API.
+type JobStatus struct {
+	Name        string         `json:"name"`                   // Name of the job
+	Source      string         `json:"source"`                 // Name of the source fetched by the job, or of the task it runs
+	Schedule    string         `json:"schedule"`               // Human-readable schedule of the job
+	NextRun     time.Time      `json:"next_run"`               // Next scheduled run according to gocron
+	Paused      bool           `json:"paused"`                 // Whether scheduled runs are skipped
+	Running     bool           `json:"running"`                // Whether a run is in progress
+	LastSuccess *models.JobRun `json:"last_success,omitempty"` // Most recent successful run
+	LastFailure *models.JobRun `json:"last_failure,omitempty"` // Most recent failed run
+}
+
+// runStats holds the counters of a job run.
+type runStats struct {
+	fetched  int // Number of posts returned by the source
+	skipped  int // Number of items the source had to skip
+	upserted int // Number of posts written to the database
+	failed   int // Number of posts whose write failed
+}
+
+// StartScheduler initializes and starts a scheduler with one job per target, fetching trending items from the
+// target's source and storing them in the target's post repository according to the source's schedule, and one
+// job per task, scheduled under the task's name or, if the configuration has no schedule for it, with the task's
+// own schedule.
+//
+// For sources that implement services.CommentSource and targets with a comments collection, the comments of the
+// top REDDIT_COMMENT_POSTS posts are fetched (up to REDDIT_COMMENT_LIMIT each), stored in the comments collection
+// and aggregated into the post's comment sentiment. For sources that implement services.TagSource and targets with
+// a tags collection, the trending tags are stored in the tags collection.
+//
+// Parameters:
+//   - targets: The sources to fetch and the repositories to store their items in.
+//   - tasks: The tasks to run besides fetching the sources.
+//   - schedules: The schedule configuration, looked up by source name.
+//   - runs: The MongoDB collection where every job run is recorded.
+//   - states: The MongoDB collection where paused jobs are stored.
+//   - lease: The started leader election lease, or nil to always run the scheduled jobs.
+//
+// Returns:
+//   - A pointer to the running Scheduler, or an error if a job cannot be scheduled.
+func StartScheduler(targets []Target, tasks []Task, schedules *config.ScheduleConfig, runs *mongo.Collection, states *mongo.Collection, lease *Lease) (*Scheduler, error) {
+	s := &Scheduler{
+		schedulers:   make(map[string]*gocron.Scheduler),
+		runs:         runs,
+		states:       states,
+		lease:        lease,
+		commentPosts: config.GetEnvInt("REDDIT_COMMENT_POSTS", 10),
+		commentLimit: config.GetEnvInt("REDDIT_COMMENT_LIMIT", 50),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for _, target := range targets {
+		schedule := schedules.For(target.Source.Name())
+		if !schedule.IsEnabled() {
+			log.Printf("Job %s is disabled", schedule.Name)
+			continue
+		}
+
+		job := &Job{Name: schedule.Name, Target: target, Schedule: schedule}
+		if err := s.add(job); err != nil {
+			return nil, fmt.Errorf("error scheduling job %s: %v", schedule.Name, err)
+		}
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		schedule, ok := schedules.Lookup(task.Name)
+		if !ok {
+			schedule = task.Schedule
+			schedule.Name = task.Name
+			schedule.Source = task.Name
+		}
+		if !schedule.IsEnabled() {
+			log.Printf("Job %s is disabled", schedule.Name)
+			continue
+		}
+
+		job := &Job{Name: schedule.Name, Task: task, Schedule: schedule}
+		if err := s.add(job); err != nil {
+			return nil, fmt.Errorf("error scheduling job %s: %v", schedule.Name, err)
+		}
+	}
+
+	// Start the schedulers asynchronously
+	for _, scheduler := range s.schedulers {
+		scheduler.StartAsync()
+	}
+	return s, nil
+}
+
+// JobSources returns the names of the sources of the targets and of the tasks, which are the names their schedules
+// are looked up by, for config.LoadScheduleConfig.
+func JobSources(targets []Target, tasks []Task) []string {
+	sources := make([]string, 0, len(targets)+len(tasks))
+	for _, target := range targets {
+		sources = append(sources, target.Source.Name())
+	}
+	for _, task := range tasks {
+		sources = append(sources, task.Name)
+	}
+	return sources
+}
+
+// add schedules a job and adds it to the jobs of the scheduler, unless another job has the same name.
+// LoadScheduleConfig already rejects duplicate names, but the configuration may have been loaded for other sources.
+func (s *Scheduler) add(job *Job) error {
+	if s.Job(job.Name) != nil {
+		return fmt.Errorf("another job is named %s", job.Name)
+	}
+	if err := s.schedule(job); err != nil {
+		return err
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// schedule creates the gocron job of a job in the scheduler of its schedule's timezone.
+func (s *Scheduler) schedule(job *Job) error {
+	schedule := job.Schedule
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	location, _ := schedule.Location()
+	jitter, _ := schedule.JitterDuration()
+
+	// Create the scheduler of the timezone on first use
+	scheduler, ok := s.schedulers[location.String()]
+	if !ok {
+		scheduler = gocron.NewScheduler(location)
+		s.schedulers[location.String()] = scheduler
+	}
+
+	run := func() {
+		// Spread the runs of jobs sharing a schedule so they don't all hit their APIs at once
+		if jitter > 0 {
+			select {
+			case <-time.After(time.Duration(rand.Int63n(int64(jitter)))):
+			case <-s.ctx.Done():
+				return
+			}
+		}
+		s.runJob(job)
+	}
+
+	// Singleton mode keeps gocron from queuing a run while the previous one is still in progress
+	var err error
+	if schedule.Cron != "" {
+		job.job, err = scheduler.Cron(schedule.Cron).Tag(job.Name).SingletonMode().Do(run)
+	} else {
+		job.job, err = scheduler.Every(schedule.IntervalDuration()).Tag(job.Name).SingletonMode().Do(run)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Scheduled job %s (%s)", job.Name, describeSchedule(schedule))
+	return nil
+}
+
+// runJob executes a scheduled run of a job, unless this replica is not the leader or the job is paused or already running.
+func (s *Scheduler) runJob(job *Job) {
+	if !s.IsLeader() {
+		return
+	}
+	paused, err := isPaused(s.ctx, s.states, job.Name)
+	if err != nil {
+		log.Printf("Skipping scheduled run of job %s: %v", job.Name, err)
+		return
+	}
+	if paused {
+		return
+	}
+	if !job.running.CompareAndSwap(false, true) {
+		log.Printf("Skipping scheduled run of job %s: a run is already in progress", job.Name)
+		return
+	}
+	defer job.running.Store(false)
+	if !s.begin() {
+		return
+	}
+	defer s.active.Done()
+
+	ctx, cancel := s.runContext()
+	defer cancel()
+
+	run, err := startRun(ctx, s.runs, job.Name)
+	if err != nil {
+		log.Printf("Failed to record start of job %s: %v", job.Name, err)
+	}
+	s.execute(ctx, job, run)
+}
+
+// runContext returns the context of a run, cancelled when the scheduler's runs are cancelled or this replica
+// loses the leadership, so that a deposed leader stops writing before the new one starts.
+func (s *Scheduler) runContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	if s.lease == nil {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(s.lease.Context(), cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// IsLeader reports whether this replica executes the scheduled runs.
+func (s *Scheduler) IsLeader() bool {
+	return s.lease == nil || s.lease.IsLeader()
+}
+
+// Trigger starts a run of the named job immediately, even if the job is paused, and returns the recorded run.
+// The run executes in the background; its outcome can be polled with Run.
+// Only the leader runs jobs, since runs in progress are only tracked per replica: it returns ErrNotLeader on
+// the other replicas, and ErrJobNotFound or ErrJobRunning if the job does not exist or is already running.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*models.JobRun, error) {
+	job := s.Job(name)
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	if !s.IsLeader() {
+		return nil, ErrNotLeader
+	}
+	if !job.running.CompareAndSwap(false, true) {
+		return nil, ErrJobRunning
+	}
+	if !s.begin() {
+		job.running.Store(false)
+		return nil, ErrStopped
+	}
+
+	// The caller needs the run ID to poll the result, so a run that cannot be recorded is not started
+	run, err := startRun(ctx, s.runs, job.Name)
+	if err != nil {
+		s.active.Done()
+		job.running.Store(false)
+		return nil, err
+	}
+
+	go func() {
+		defer job.running.Store(false)
+		defer s.active.Done()
+		ctx, cancel := s.runContext()
+		defer cancel()
+		s.execute(ctx, job, run)
+	}()
+	return run, nil
+}
+
+// Pause stops the scheduled runs of the named job on every replica until Resume is called.
+// A run in progress is not interrupted. Like Trigger, it returns ErrNotLeader on the replicas that are not the leader.
+func (s *Scheduler) Pause(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, true)
+}
+
+// Resume restarts the scheduled runs of the named job. Like Trigger, it returns ErrNotLeader on the replicas that
+// are not the leader.
+func (s *Scheduler) Resume(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, false)
+}
+
+// setPaused stores whether the named job is paused.
+func (s *Scheduler) setPaused(ctx context.Context, name string, paused bool) error {
+	if s.Job(name) == nil {
+		return ErrJobNotFound
+	}
+	if !s.IsLeader() {
+		return ErrNotLeader
+	}
+	if err := setPaused(ctx, s.states, name, paused); err != nil {
+		return err
+	}
+
+	if paused {
+		log.Printf("Paused job %s", name)
+	} else {
+		log.Printf("Resumed job %s", name)
+	}
+	return nil
+}
+
+// cancelGracePeriod is how long Stop waits for cancelled runs to return before giving up on them.
+const cancelGracePeriod = 5 * time.Second
+
+// Stop stops scheduling new runs and waits for the runs in progress to finish.
+// If ctx expires first, the runs in progress are cancelled and given cancelGracePeriod to return, and ctx's error
+// is returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, scheduler := range s.schedulers {
+			scheduler.Stop() // Blocks until the gocron jobs in progress return
+		}
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		log.Println("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		log.Println("Scheduler stop timed out, cancelling runs in progress")
+	}
+
+	// Cancelled runs abort their requests and writes; wait for them to return so nothing writes after Stop
+	select {
+	case <-done:
+		log.Println("Scheduler stopped after cancelling runs in progress")
+	case <-time.After(cancelGracePeriod):
+		log.Println("Runs in progress did not return after being cancelled")
+	}
+	return ctx.Err()
+}
+
+// begin registers a run in progress, or returns false if the scheduler is stopped.
+func (s *Scheduler) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.active.Add(1)
+	return true
+}
+
+// execute fetches and stores the job's target, or runs its task, and records the outcome of the run.
+// The caller must have marked the job as running and registered the run with begin.
+func (s *Scheduler) execute(ctx context.Context, job *Job, run *models.JobRun) {
+	if job.Task != nil {
+		finishRun(s.runs, run, job.Task.Run(ctx, run))
+		return
+	}
+
+	stats, err := s.fetchAndStore(ctx, job.Target)
+	run.PostsFetched = stats.fetched
+	run.PostsSkipped = stats.skipped
+	run.PostsUpserted = stats.upserted
+	run.PostsFailed = stats.failed
+
+	finishRun(s.runs, run, err)
+}
+
+// Job returns the job with the given name, or nil if there is none.
+func (s *Scheduler) Job(name string) *Job {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Jobs returns the status of every job, including its next scheduled run and its last success and failure.
+// Only the leader knows which runs are in progress, so it returns ErrNotLeader on the other replicas.
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobStatus, error) {
+	if !s.IsLeader() {
+		return nil, ErrNotLeader
+	}
+	paused, err := pausedJobs(ctx, s.states)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []JobStatus{}
+	for _, job := range s.jobs {
+		status := JobStatus{
+			Name:     job.Name,
+			Source:   job.Schedule.Source,
+			Schedule: describeSchedule(job.Schedule),
+			NextRun:  job.job.NextRun(),
+			Paused:   paused[job.Name],
+			Running:  job.running.Load(),
+		}
+
+		var err error
+		if status.LastSuccess, err = lastRunWithStatus(ctx, s.runs, job.Name, models.JobRunSuccess); err != nil {
+			return nil, err
+		}
+		if status.LastFailure, err = lastRunWithStatus(ctx, s.runs, job.Name, models.JobRunFailed); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Runs returns the most recent runs of the named job, newest first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int64) ([]models.JobRun, error) {
+	return ListRuns(ctx, s.runs, name, limit)
+}
+
+// Run returns the run of the named job with the given ID, or nil if there is none.
+func (s *Scheduler) Run(ctx context.Context, name string, id primitive.ObjectID) (*models.JobRun, error) {
+	return getRun(ctx, s.runs, name, id)
+}
+
+// describeSchedule returns a human-readable summary of a schedule for logging.
+func describeSchedule(schedule config.JobSchedule) string {
+	description := "every " + schedule.Interval
+	if schedule.Cron != "" {
+		description = "cron " + schedule.Cron
+	}
+	if schedule.Timezone != "" {
+		description += " " + schedule.Timezone
+	}
+	if schedule.Jitter != "" {
+		description += ", jitter " + schedule.Jitter
+	}
+	return description
+}
+
+// fetchAndStore fetches the trending items of a single target and stores them in its post repository.
+// It returns the counters of the run and the error that made it fail, if any.
+// Comment and tag ingestion failures are logged but do not fail the run.
+// Cancelling ctx aborts the requests and writes in flight; every post is written in a single update,
+// so a cancelled run never leaves a post half-written.
+func (s *Scheduler) fetchAndStore(ctx context.Context, target Target) (runStats, error) {
+	name := target.Source.Name()
+	var stats runStats
+
+	// Fetch trending items from the source
+	result, err := target.Source.Fetch(ctx)
+	if err != nil {
+		log.Printf("Error fetching trending items from %s: %v", name, err)
+		return stats, fmt.Errorf("failed to fetch trending items: %v", err)
+	}
+	stats.fetched = len(result.Posts)
+	stats.skipped = result.Skipped
+
+	// Report the items that could not be normalized without aborting the run
+	for _, childErr := range result.Errors {
+		log.Printf("Skipped item from %s: %v", name, childErr)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return stats, fmt.Errorf("run cancelled before storing posts: %v", err)
+	}
+
+	// Store the fetched posts in the target's post repository
+	stored, err := services.StoreRedditPosts(ctx, target.Posts, target.Snapshots, result.Posts)
+	if err != nil {
+		log.Printf("Error storing posts from %s: %v", name, err)
+		return stats, fmt.Errorf("failed to store posts: %v", err)
+	}
+	stats.upserted = stored.Inserted + stored.Modified
+	stats.failed = stored.Failed
+
+	// Report the posts that could not be written without aborting the run
+	for _, writeErr := range stored.Errors {
+		log.Printf("Failed to store post from %s: %v", name, writeErr)
+	}
+
+	// Store the trending tags if the source reports them
+	if tagSource, ok := target.Source.(services.TagSource); ok && target.Tags != nil {
+		fetchAndStoreTags(ctx, target, tagSource)
+	}
+
+	// Ingest the comments of the top posts if the source supports it; posts are ordered by rank
+	commentSource, ok := target.Source.(services.CommentSource)
+	if !ok || target.Comments == nil {
+		return stats, nil
+	}
+	for i, post := range result.Posts {
+		if i >= s.commentPosts {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			log.Printf("Stopped ingesting comments from %s: %v", name, err)
+			break
+		}
+		fetchAndStoreComments(ctx, target, commentSource, post, s.commentLimit)
+	}
+	return stats, nil
+}
+
+// fetchAndStoreComments fetches the top comments of a single post and stores them with their sentiment.
+func fetchAndStoreComments(ctx context.Context, target Target, source services.CommentSource, post models.TrendingPost, limit int) {
+	// Fetch the flattened comment tree of the post
+	comments, err := source.FetchComments(ctx, post.ID, limit)
+	if err != nil {
+		log.Printf("Error fetching comments for post %s from %s: %v", post.ID, source.Name(), err)
+		return
+	}
+
+	// Store the comments and the aggregate sentiment on the post
+	_, err = services.StoreRedditComments(ctx, target.Comments, target.Posts, post.Source, post.ID, comments)
+	if err != nil {
+		log.Printf("Error storing comments for post %s from %s: %v", post.ID, source.Name(), err)
+	}
+}
+
+// fetchAndStoreTags fetches the trending tags of a source and stores them in the target's tags collection.
+func fetchAndStoreTags(ctx context.Context, target Target, source services.TagSource) {
+	tags, err := source.FetchTags(ctx)
+	if err != nil {
+		log.Printf("Error fetching trending tags from %s: %v", source.Name(), err)
+		return
+	}
+
+	if err := services.StoreTrendingTags(ctx, target.Tags, tags); err != nil {
+		log.Printf("Error storing trending tags from %s: %v", source.Name(), err)
+	}
+}
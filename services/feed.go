@@ -0,0 +1,317 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SourceFeed    = "feed"             // Value of TrendingPost.Source for entries fetched from RSS and Atom feeds
+	FeedUserAgent = "TrendlensBot/0.1" // User agent sent with feed requests
+)
+
+// rssDateLayouts are the date layouts found in the wild in RSS pubDate elements, tried in order.
+var rssDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+// feedState holds the last successful response of a feed: its validators, used for conditional GETs,
+// and its entries, returned again while the feed has not changed.
+type feedState struct {
+	ETag         string                // Value of the ETag header
+	LastModified string                // Value of the Last-Modified header
+	Posts        []models.TrendingPost // Entries parsed from the response
+}
+
+// FeedSource is the Source polling a list of RSS 2.0 and Atom 1.0 feeds.
+//
+// It remembers the ETag and Last-Modified validators of every feed and sends them back on the next poll,
+// so feeds that did not change answer with 304 Not Modified and are not downloaded again. Their entries are
+// still returned from the last response, so that entries still in the feed are stored as observed again
+// instead of aging out as inactive.
+type FeedSource struct {
+	urls       []string             // URLs of the feeds to poll
+	httpClient *http.Client         // HTTP client used to send requests
+	mu         sync.Mutex           // Guards feeds
+	feeds      map[string]feedState // Last successful response of every feed, keyed by feed URL
+}
+
+// NewFeedSource creates a FeedSource polling the given feed URLs.
+// A nil httpClient defaults to a client with a 10 second timeout.
+func NewFeedSource(urls []string, httpClient *http.Client) *FeedSource {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &FeedSource{
+		urls:       urls,
+		httpClient: httpClient,
+		feeds:      make(map[string]feedState),
+	}
+}
+
+// Name identifies the source as "feed".
+func (s *FeedSource) Name() string {
+	return SourceFeed
+}
+
+// Fetch polls every feed and normalizes its entries into trending posts.
+// A feed that fails to load or parse is reported as a skipped item instead of failing the whole fetch.
+func (s *FeedSource) Fetch(ctx context.Context) (*ListingResult, error) {
+	result := &ListingResult{}
+	for i, feedURL := range s.urls {
+		// Stop polling once cancelled rather than recording every remaining feed as failed
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		posts, err := s.fetchFeed(ctx, feedURL)
+		if err != nil {
+			// Record the failure and move on to the next feed
+			result.Skipped++
+			result.Errors = append(result.Errors, &ChildError{Index: i, Kind: SourceFeed, Err: fmt.Errorf("%s: %v", feedURL, err)})
+			continue
+		}
+		result.Count += len(posts)
+		result.Posts = append(result.Posts, posts...)
+	}
+	return result, nil
+}
+
+// fetchFeed sends a conditional GET for a single feed and parses its entries.
+// It returns the entries of the last poll when the feed has not changed since.
+func (s *FeedSource) fetchFeed(ctx context.Context, feedURL string) ([]models.TrendingPost, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed request: %v", err)
+	}
+	req.Header.Set("User-Agent", FeedUserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	// Send back the validators of the last successful response
+	s.mu.Lock()
+	last := s.feeds[feedURL]
+	s.mu.Unlock()
+	if last.ETag != "" {
+		req.Header.Set("If-None-Match", last.ETag)
+	}
+	if last.LastModified != "" {
+		req.Header.Set("If-Modified-Since", last.LastModified)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send feed request: %v", err)
+	}
+	defer res.Body.Close() // Ensure response body is closed
+
+	if res.StatusCode == http.StatusNotModified {
+		return slices.Clone(last.Posts), nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %v", err)
+	}
+
+	posts, err := ParseFeed(body, feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember the response only once the feed was parsed, so a broken response is fetched again in full
+	s.mu.Lock()
+	s.feeds[feedURL] = feedState{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Posts:        posts,
+	}
+	s.mu.Unlock()
+
+	return slices.Clone(posts), nil
+}
+
+// ParseFeed parses an RSS 2.0 or Atom 1.0 document and normalizes its entries into trending posts,
+// ranked by their position in the feed. The feed URL is used to resolve relative links.
+func ParseFeed(body []byte, feedURL string) ([]models.TrendingPost, error) {
+	root, err := feedRootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var feed models.RSSFeed
+		if err := newFeedDecoder(body).Decode(&feed); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %v", err)
+		}
+		return normalizeRSSFeed(feed, feedURL), nil
+	case "feed":
+		var feed models.AtomFeed
+		if err := newFeedDecoder(body).Decode(&feed); err != nil {
+			return nil, fmt.Errorf("failed to parse Atom feed: %v", err)
+		}
+		return normalizeAtomFeed(feed, feedURL), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format <%s>", root)
+	}
+}
+
+// newFeedDecoder creates a lenient XML decoder for a feed document.
+// Feeds in the wild are not always well-formed and often use HTML entities such as &nbsp;.
+func newFeedDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// feedRootElement returns the local name of the root element of an XML document.
+func feedRootElement(body []byte) (string, error) {
+	decoder := newFeedDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to read feed: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// normalizeRSSFeed turns the items of an RSS channel into trending posts.
+func normalizeRSSFeed(feed models.RSSFeed, feedURL string) []models.TrendingPost {
+	var posts []models.TrendingPost
+	for i, item := range feed.Channel.Items {
+		link := resolveFeedLink(feedURL, strings.TrimSpace(item.Link))
+
+		// Prefer the GUID as identity; fall back to the link and then the title
+		key := firstNonEmpty(item.GUID, link, item.Title)
+		if key == "" {
+			continue
+		}
+
+		author := firstNonEmpty(item.Creator, item.Author)
+		posts = append(posts, newFeedPost(feedURL, key, strings.TrimSpace(feed.Channel.Title), item.Title, link,
+			firstNonEmpty(item.Comments, link), author, parseFeedTime(item.PubDate, rssDateLayouts), item.Description, i+1))
+	}
+	return posts
+}
+
+// normalizeAtomFeed turns the entries of an Atom feed into trending posts.
+func normalizeAtomFeed(feed models.AtomFeed, feedURL string) []models.TrendingPost {
+	var posts []models.TrendingPost
+	for i, entry := range feed.Entries {
+		link := resolveFeedLink(feedURL, atomAlternateLink(entry.Links))
+
+		// The Atom ID is mandatory, but fall back to the link for sloppy feeds
+		key := firstNonEmpty(entry.ID, link, entry.Title)
+		if key == "" {
+			continue
+		}
+
+		author := ""
+		if len(entry.Authors) > 0 {
+			author = entry.Authors[0].Name
+		}
+		published := parseFeedTime(firstNonEmpty(entry.Published, entry.Updated), []string{time.RFC3339})
+		posts = append(posts, newFeedPost(feedURL, key, strings.TrimSpace(feed.Title), entry.Title, link, link,
+			author, published, firstNonEmpty(entry.Summary, entry.Content), i+1))
+	}
+	return posts
+}
+
+// newFeedPost builds the trending post of a feed entry.
+// The ID is derived from the feed URL and the entry's key so that it is stable across polls and unique across feeds.
+func newFeedPost(feedURL, key, feedTitle, title, link, permalink, author string, published time.Time, summary string, rank int) models.TrendingPost {
+	hash := sha1.Sum([]byte(feedURL + "\n" + key))
+
+	domain := ""
+	if parsed, err := url.Parse(link); err == nil {
+		domain = parsed.Hostname()
+	}
+
+	return models.TrendingPost{
+		ID:        hex.EncodeToString(hash[:]),
+		Source:    SourceFeed,
+		Name:      strings.TrimSpace(title),
+		Rank:      rank,
+		Listing:   feedURL,
+		Community: feedTitle,
+		Permalink: permalink,
+		URL:       link,
+		Author:    strings.TrimSpace(author),
+		CreatedAt: published,
+		Domain:    domain,
+		Selftext:  summary,
+	}
+}
+
+// atomAlternateLink returns the href of the "alternate" link of an Atom entry, which is the entry's web page.
+func atomAlternateLink(links []models.AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// resolveFeedLink resolves a possibly relative entry link against the feed URL.
+func resolveFeedLink(feedURL string, link string) string {
+	base, err := url.Parse(feedURL)
+	if err != nil || link == "" {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseFeedTime parses a feed date with the first matching layout, returning the zero time if none matches.
+func parseFeedTime(value string, layouts []string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// firstNonEmpty returns the first of the values that is not blank, trimmed.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title> Go Blog </title>
+    <link>https://go.dev/blog</link>
+    <item>
+      <title>Go 1.23 is released</title>
+      <link>/blog/go1.23</link>
+      <guid>tag:go.dev,2024:go1.23</guid>
+      <pubDate>Tue, 13 Aug 2024 10:00:00 +0000</pubDate>
+      <dc:creator>The Go Team</dc:creator>
+      <description>Range over func&nbsp;and more</description>
+      <comments>https://news.example.com/go1.23</comments>
+    </item>
+    <item>
+      <title>No GUID</title>
+      <link>https://go.dev/blog/no-guid</link>
+      <pubDate>Mon, 5 Aug 2024 09:30:00 GMT</pubDate>
+      <author>gopher@example.com</author>
+    </item>
+    <item>
+      <description>An item without identity is skipped</description>
+    </item>
+  </channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Atom</title>
+  <link href="https://example.org/"/>
+  <entry>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <title>Atom-Powered Robots Run Amok</title>
+    <link rel="edit" href="https://example.org/edit/1"/>
+    <link rel="alternate" href="https://example.org/2003/12/13/atom03"/>
+    <updated>2003-12-13T18:30:02Z</updated>
+    <author><name>John Doe</name></author>
+    <content>Some text.</content>
+  </entry>
+  <entry>
+    <id>urn:uuid:2</id>
+    <title>Second</title>
+    <link href="posts/2"/>
+    <published>2003-12-14T08:00:00+01:00</published>
+    <updated>2003-12-15T00:00:00Z</updated>
+    <summary>A summary</summary>
+  </entry>
+</feed>`
+
+func TestParseFeedRSS(t *testing.T) {
+	posts, err := ParseFeed([]byte(testRSSFeed), "https://go.dev/blog/feed.atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	post := posts[0]
+	if post.Source != SourceFeed || post.Name != "Go 1.23 is released" || post.Rank != 1 ||
+		post.URL != "https://go.dev/blog/go1.23" || post.Permalink != "https://news.example.com/go1.23" ||
+		post.Domain != "go.dev" || post.Author != "The Go Team" || post.Community != "Go Blog" ||
+		post.Listing != "https://go.dev/blog/feed.atom" || post.Selftext != "Range over func and more" ||
+		!post.CreatedAt.Equal(time.Date(2024, 8, 13, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("RSS item normalized as %+v", post)
+	}
+
+	second := posts[1]
+	if second.Rank != 2 || second.Author != "gopher@example.com" || second.Permalink != second.URL ||
+		!second.CreatedAt.Equal(time.Date(2024, 8, 5, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("RSS item without GUID normalized as %+v", second)
+	}
+	if len(post.ID) != 40 || post.ID == second.ID {
+		t.Fatalf("posts have IDs %q and %q, want distinct SHA-1 hashes", post.ID, second.ID)
+	}
+
+	// The ID only depends on the feed and the entry, so it is stable across polls and distinct across feeds
+	again, _ := ParseFeed([]byte(testRSSFeed), "https://go.dev/blog/feed.atom")
+	other, _ := ParseFeed([]byte(testRSSFeed), "https://mirror.example.com/feed")
+	if again[0].ID != post.ID || other[0].ID == post.ID {
+		t.Fatalf("IDs %q, %q and %q, want stable IDs scoped to the feed", post.ID, again[0].ID, other[0].ID)
+	}
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	posts, err := ParseFeed([]byte(testAtomFeed), "https://example.org/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	post := posts[0]
+	if post.Name != "Atom-Powered Robots Run Amok" || post.URL != "https://example.org/2003/12/13/atom03" ||
+		post.Author != "John Doe" || post.Selftext != "Some text." || post.Community != "Example Atom" ||
+		!post.CreatedAt.Equal(time.Date(2003, 12, 13, 18, 30, 2, 0, time.UTC)) {
+		t.Fatalf("Atom entry normalized as %+v", post)
+	}
+
+	// Relative links are resolved against the feed and the publication time wins over the update time
+	second := posts[1]
+	if second.URL != "https://example.org/posts/2" || second.Selftext != "A summary" ||
+		!second.CreatedAt.Equal(time.Date(2003, 12, 14, 7, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Atom entry normalized as %+v", second)
+	}
+}
+
+func TestParseFeedAtomWithoutNamespace(t *testing.T) {
+	feed := strings.Replace(testAtomFeed, ` xmlns="http://www.w3.org/2005/Atom"`, "", 1)
+	posts, err := ParseFeed([]byte(feed), "https://example.org/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || posts[0].Name != "Atom-Powered Robots Run Amok" || posts[1].URL != "https://example.org/posts/2" {
+		t.Fatalf("Atom feed without namespace normalized as %+v", posts)
+	}
+}
+
+func TestParseFeedRejectsUnknownFormat(t *testing.T) {
+	if _, err := ParseFeed([]byte(`<html><body>Not a feed</body></html>`), "https://example.org/"); err == nil {
+		t.Fatal("HTML document parsed as a feed")
+	}
+	if _, err := ParseFeed([]byte(`not XML`), "https://example.org/"); err == nil {
+		t.Fatal("non-XML document parsed as a feed")
+	}
+}
+
+func TestFeedSourceConditionalGet(t *testing.T) {
+	var requests, notModified atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, testRSSFeed)
+	})
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-Modified-Since") == "Sat, 13 Dec 2003 18:30:02 GMT" {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", "Sat, 13 Dec 2003 18:30:02 GMT")
+		fmt.Fprint(w, testAtomFeed)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source := NewFeedSource([]string{server.URL + "/rss", server.URL + "/atom", server.URL + "/broken"}, nil)
+
+	// The first poll fetches both feeds in full; the broken feed is skipped without failing the fetch
+	result, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Posts) != 4 || result.Skipped != 1 || notModified.Load() != 0 {
+		t.Fatalf("first poll got %d posts with %d skipped, want 4 posts with 1 skipped", len(result.Posts), result.Skipped)
+	}
+
+	first := result.Posts
+
+	// The second poll sends the validators back and both feeds answer 304 Not Modified
+	result, err = source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notModified.Load() != 2 || requests.Load() != 4 {
+		t.Fatalf("second poll got %d of %d requests answered 304, want 2 of 4", notModified.Load(), requests.Load())
+	}
+
+	// The entries of the unchanged feeds are returned again, so that they are stored as still observed
+	if len(result.Posts) != 4 || result.Skipped != 1 {
+		t.Fatalf("second poll got %d posts with %d skipped, want the 4 posts of the unchanged feeds", len(result.Posts), result.Skipped)
+	}
+	for i, post := range result.Posts {
+		if post != first[i] {
+			t.Fatalf("second poll returned %+v, want %+v", post, first[i])
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HackerNewsAPIURL  = "https://hacker-news.firebaseio.com/v0" // Base URL of the public Hacker News API
+	HackerNewsItemURL = "https://news.ycombinator.com/item?id=" // URL prefix of an item's discussion page
+
+	SourceHackerNews = "hackernews" // Value of TrendingPost.Source for items fetched from Hacker News
+
+	hackerNewsWorkers = 8 // Number of items fetched concurrently
+)
+
+// hackerNewsLists maps the supported story lists to the endpoint serving their IDs.
+var hackerNewsLists = map[string]string{
+	"top":  "topstories",
+	"new":  "newstories",
+	"best": "beststories",
+	"ask":  "askstories",
+	"show": "showstories",
+}
+
+// HackerNewsSource is the Source fetching one of the Hacker News story lists through the public JSON API.
+type HackerNewsSource struct {
+	baseURL    string       // Base URL of the API, overridable to point at a local fake
+	list       string       // Story list to fetch (top, new, best, ask or show)
+	maxItems   int          // Maximum number of items to fetch from the list
+	httpClient *http.Client // HTTP client used to send requests
+}
+
+// NewHackerNewsSource creates a HackerNewsSource fetching up to maxItems stories of the given list.
+// An empty baseURL defaults to HackerNewsAPIURL and a nil httpClient to a client with a 10 second timeout.
+func NewHackerNewsSource(baseURL string, list string, maxItems int, httpClient *http.Client) (*HackerNewsSource, error) {
+	if _, ok := hackerNewsLists[list]; !ok {
+		return nil, fmt.Errorf("unsupported Hacker News list %q", list)
+	}
+	if baseURL == "" {
+		baseURL = HackerNewsAPIURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &HackerNewsSource{
+		baseURL:    baseURL,
+		list:       list,
+		maxItems:   maxItems,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Name identifies the source as "hackernews:<list>".
+func (s *HackerNewsSource) Name() string {
+	return SourceHackerNews + ":" + s.list
+}
+
+// Fetch retrieves the IDs of the story list, then the items themselves, and normalizes them into trending posts
+// ranked by their position in the list. Items that fail to load, are dead or deleted, or are not stories are skipped.
+func (s *HackerNewsSource) Fetch(ctx context.Context) (*ListingResult, error) {
+	var ids []int
+	if err := s.getJSON(ctx, "/"+hackerNewsLists[s.list]+".json", &ids); err != nil {
+		return nil, err
+	}
+	if s.maxItems > 0 && len(ids) > s.maxItems {
+		ids = ids[:s.maxItems]
+	}
+
+	// Fetch the items concurrently, keeping their position in the list
+	items := make([]*models.HackerNewsItem, len(ids))
+	errs := make([]error, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < hackerNewsWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var item models.HackerNewsItem
+				if err := s.getJSON(ctx, "/item/"+strconv.Itoa(ids[i])+".json", &item); err != nil {
+					errs[i] = err
+					continue
+				}
+				items[i] = &item
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// A cancelled fetch would otherwise report every remaining item as skipped
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &ListingResult{Count: len(ids)}
+	for i, item := range items {
+		err := errs[i]
+		if err == nil {
+			err = validateHackerNewsItem(item)
+		}
+		if err != nil {
+			// Record the failure and move on to the next item
+			result.Skipped++
+			result.Errors = append(result.Errors, &ChildError{Index: i, Kind: SourceHackerNews, Err: err})
+			continue
+		}
+
+		post := normalizeHackerNewsItem(item)
+		post.Rank = i + 1
+		post.Listing = s.list
+		result.Posts = append(result.Posts, post)
+	}
+
+	return result, nil
+}
+
+// getJSON sends a GET request for the given API path and decodes the JSON response into target.
+func (s *HackerNewsSource) getJSON(ctx context.Context, path string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Hacker News request: %v", err)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Hacker News request: %v", err)
+	}
+	defer res.Body.Close() // Ensure response body is closed
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from Hacker News API for %s", res.StatusCode, path)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Hacker News response: %v", err)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal Hacker News response: %v", err)
+	}
+	return nil
+}
+
+// validateHackerNewsItem reports why an item cannot be turned into a trending post, if it cannot.
+func validateHackerNewsItem(item *models.HackerNewsItem) error {
+	switch {
+	case item == nil || item.ID == 0:
+		return fmt.Errorf("item not found")
+	case item.Deleted || item.Dead:
+		return fmt.Errorf("item %d is deleted or dead", item.ID)
+	case item.Type != "story" && item.Type != "job" && item.Type != "poll":
+		return fmt.Errorf("item %d has unsupported type %q", item.ID, item.Type)
+	}
+	return nil
+}
+
+// normalizeHackerNewsItem turns a Hacker News item into a trending post.
+// Hacker News only exposes a score, so it is used for both the score and the upvotes.
+func normalizeHackerNewsItem(item *models.HackerNewsItem) models.TrendingPost {
+	id := strconv.Itoa(item.ID)
+	permalink := HackerNewsItemURL + id
+
+	// Text posts such as Ask HN have no outbound URL and link to their discussion instead
+	link := item.URL
+	domain := ""
+	if link == "" {
+		link = permalink
+	} else if parsed, err := url.Parse(link); err == nil {
+		domain = parsed.Hostname()
+	}
+
+	return models.TrendingPost{
+		ID:          id,
+		Source:      SourceHackerNews,
+		Name:        item.Title,
+		VolumeUp:    item.Score,
+		Permalink:   permalink,
+		URL:         link,
+		Author:      item.By,
+		CreatedAt:   time.Unix(item.Time, 0).UTC(),
+		NumComments: item.Descendants,
+		Score:       item.Score,
+		Domain:      domain,
+		IsSelf:      item.URL == "",
+		Selftext:    StripHTML(item.Text),
+	}
+}
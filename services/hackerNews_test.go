@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newFakeHackerNews starts a fake Hacker News API serving the given story list and items.
+// Items missing from the map are served as null, like deleted IDs of the real API.
+func newFakeHackerNews(t *testing.T, list string, ids string, items map[int]string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+hackerNewsLists[list]+".json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ids)
+	})
+	mux.HandleFunc("/item/{file}", func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		if _, err := fmt.Sscanf(r.PathValue("file"), "%d.json", &id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if id == 500 {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		item, ok := items[id]
+		if !ok {
+			item = "null"
+		}
+		fmt.Fprint(w, item)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestHackerNewsSourceFetch(t *testing.T) {
+	baseURL := newFakeHackerNews(t, "top", "[1, 2, 3, 4, 5, 500, 7, 8]", map[int]string{
+		1: `{"id": 1, "type": "story", "by": "pg", "time": 1700000000, "title": "A story", "url": "https://example.com/a", "score": 42, "descendants": 7}`,
+		2: `{"id": 2, "type": "story", "by": "dang", "time": 1700000100, "title": "Ask HN: Why?", "text": "Because<p>It&#x27;s <i>fun</i>", "score": 5}`,
+		3: `{"id": 3, "type": "comment", "by": "x", "text": "A comment"}`,
+		4: `{"id": 4, "type": "story", "dead": true}`,
+		7: `{"id": 7, "type": "job", "title": "Hiring", "url": "https://jobs.example.com", "score": 1}`,
+		8: `{"id": 8, "type": "story", "title": "Beyond the limit"}`,
+	})
+
+	source, err := NewHackerNewsSource(baseURL, "top", 7, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Items 3 (a comment), 4 (dead), 5 (missing) and 500 (failing) are skipped; item 8 is beyond the limit
+	if result.Count != 7 || result.Skipped != 4 || len(result.Posts) != 3 {
+		t.Fatalf("got %d posts out of %d with %d skipped (%v), want 3 out of 7 with 4 skipped",
+			len(result.Posts), result.Count, result.Skipped, result.Errors)
+	}
+
+	story := result.Posts[0]
+	if story.ID != "1" || story.Source != SourceHackerNews || story.Rank != 1 || story.Listing != "top" ||
+		story.Name != "A story" || story.Score != 42 || story.VolumeUp != 42 || story.NumComments != 7 ||
+		story.Domain != "example.com" || story.IsSelf || story.Author != "pg" ||
+		story.Permalink != HackerNewsItemURL+"1" || !story.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("story normalized as %+v", story)
+	}
+
+	ask := result.Posts[1]
+	if ask.Rank != 2 || !ask.IsSelf || ask.URL != HackerNewsItemURL+"2" || ask.Selftext != "Because\nIt's fun" {
+		t.Fatalf("text post normalized as %+v", ask)
+	}
+	if job := result.Posts[2]; job.ID != "7" || job.Rank != 7 {
+		t.Fatalf("job normalized as %+v, want rank 7", job)
+	}
+}
+
+func TestHackerNewsSourceCancelled(t *testing.T) {
+	baseURL := newFakeHackerNews(t, "new", "[1]", map[int]string{1: `{"id": 1, "type": "story"}`})
+	source, err := NewHackerNewsSource(baseURL, "new", 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, err := source.Fetch(ctx); err == nil {
+		t.Fatalf("cancelled Fetch returned %+v", result)
+	}
+}
+
+func TestNewHackerNewsSourceRejectsUnknownList(t *testing.T) {
+	if _, err := NewHackerNewsSource("", "trending", 10, nil); err == nil {
+		t.Fatal("unknown list accepted")
+	}
+}
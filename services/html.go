@@ -0,0 +1,24 @@
+package services
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlBreakPattern      = regexp.MustCompile(`(?i)<br\s*/?>|<p\b[^>]*>|</p>|</li>|</h[1-6]>`) // Tags ending a line of text
+	htmlTagPattern        = regexp.MustCompile(`<[^>]*>`)                                       // Any remaining tag
+	htmlBlankLinesPattern = regexp.MustCompile(`\n{3,}`)                                        // Runs of blank lines
+)
+
+// StripHTML converts an HTML fragment, such as the content of a Mastodon status or the text of a Hacker News
+// item, into plain text. Line-ending tags become newlines, including opening paragraph tags, since Hacker News
+// separates paragraphs with a bare <p>; every other tag is removed and entities are unescaped.
+func StripHTML(fragment string) string {
+	text := htmlBreakPattern.ReplaceAllString(fragment, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = htmlBlankLinesPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
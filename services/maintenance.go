@@ -0,0 +1,134 @@
+package services
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"time"
+)
+
+// RetentionPolicy defines how long the vote history and the posts are kept.
+//
+// Raw vote snapshots are kept for Raw, then rolled up into hourly points, which are kept until Hourly before
+// being rolled up into daily points. Daily points are kept as long as their post. Posts that have not been
+// observed by any scrape for Inactive are deleted with their vote history and comments.
+type RetentionPolicy struct {
+	Raw      time.Duration // How long raw snapshots are kept
+	Hourly   time.Duration // How long hourly points are kept
+	Inactive time.Duration // How long a post is kept after it was last observed
+}
+
+// RetentionPolicyFromEnv reads the retention policy from RETENTION_RAW (default 7 days), RETENTION_HOURLY
+// (default 90 days) and RETENTION_INACTIVE (default 180 days), written as Go durations (e.g., "168h").
+func RetentionPolicyFromEnv() RetentionPolicy {
+	return RetentionPolicy{
+		Raw:      config.GetEnvDuration("RETENTION_RAW", 7*24*time.Hour),
+		Hourly:   config.GetEnvDuration("RETENTION_HOURLY", 90*24*time.Hour),
+		Inactive: config.GetEnvDuration("RETENTION_INACTIVE", 180*24*time.Hour),
+	}
+}
+
+// Validate checks that every tier is kept longer than the previous one, so that data is rolled up before it is
+// deleted, and that raw snapshots are kept for at least an hour, so that only complete hours are rolled up.
+func (p RetentionPolicy) Validate() error {
+	if p.Raw < time.Hour {
+		return fmt.Errorf("raw retention %s is shorter than an hour", p.Raw)
+	}
+	if p.Hourly <= p.Raw {
+		return fmt.Errorf("hourly retention %s must be longer than raw retention %s", p.Hourly, p.Raw)
+	}
+	if p.Inactive <= p.Raw {
+		return fmt.Errorf("inactive retention %s must be longer than raw retention %s", p.Inactive, p.Raw)
+	}
+	return nil
+}
+
+// PostStore is a post repository maintained by RunMaintenance, with the collection of its posts' comments.
+type PostStore struct {
+	Posts    repository.PostRepository // The repository of the posts
+	Comments *mongo.Collection         // The collection of the posts' comments, nil if the source has none
+}
+
+// RunMaintenance applies the retention policy at the given time:
+//   - the raw snapshots older than the raw retention are rolled up into hourly points, and the hourly points
+//     older than the hourly retention into daily points; cutoffs are rounded down to whole hours and days
+//   - the posts of every store not observed within the inactive retention are deleted, after their vote
+//     history and comments, so that an interrupted run leaves nothing behind that the next run cannot find
+//
+// With dryRun set nothing is written and the report counts what would be done. It returns the report of what
+// was done before the first error along with the error.
+func RunMaintenance(ctx context.Context, snapshots repository.SnapshotRepository, stores []PostStore, policy RetentionPolicy, now time.Time, dryRun bool) (*models.MaintenanceReport, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid retention policy: %v", err)
+	}
+
+	now = now.UTC()
+	report := &models.MaintenanceReport{
+		DryRun:         dryRun,
+		RawCutoff:      now.Add(-policy.Raw).Truncate(time.Hour),
+		HourlyCutoff:   now.Add(-policy.Hourly).Truncate(24 * time.Hour),
+		InactiveCutoff: now.Add(-policy.Inactive),
+	}
+
+	// Downsample the vote history
+	compaction, err := snapshots.Compact(ctx, repository.CompactionCutoffs{Raw: report.RawCutoff, Hourly: report.HourlyCutoff}, dryRun)
+	if compaction != nil {
+		report.RawCompacted = compaction.RawCompacted
+		report.HourlyWritten = compaction.HourlyWritten
+		report.HourlyCompacted = compaction.HourlyCompacted
+		report.DailyWritten = compaction.DailyWritten
+	}
+	if err != nil {
+		return report, fmt.Errorf("failed to compact vote history: %v", err)
+	}
+
+	// Delete the inactive posts with their vote history and comments
+	for _, store := range stores {
+		inactive, err := store.Posts.Inactive(ctx, report.InactiveCutoff)
+		if err != nil {
+			return report, err
+		}
+		if len(inactive) == 0 {
+			continue
+		}
+
+		keys := make([]string, len(inactive))
+		postIDs := make([]string, len(inactive))
+		for i, post := range inactive {
+			keys[i] = post.ID
+			postIDs[i] = post.PostID
+		}
+
+		deleted, err := snapshots.Delete(ctx, keys, dryRun)
+		report.SeriesDeleted += deleted
+		if err != nil {
+			return report, err
+		}
+		if store.Comments != nil {
+			deleted, err := DeleteRedditComments(ctx, store.Comments, postIDs, dryRun)
+			report.CommentsDeleted += deleted
+			if err != nil {
+				return report, err
+			}
+		}
+		if dryRun {
+			report.PostsDeleted += len(keys)
+			continue
+		}
+		deleted, err = store.Posts.Delete(ctx, keys)
+		report.PostsDeleted += deleted
+		if err != nil {
+			return report, err
+		}
+	}
+
+	log.Printf("Maintenance (dry run %t): %d raw snapshots into %d hourly points, %d hourly points into %d daily points, "+
+		"%d inactive posts deleted with %d vote history entries and %d comments",
+		dryRun, report.RawCompacted, report.HourlyWritten, report.HourlyCompacted, report.DailyWritten,
+		report.PostsDeleted, report.SeriesDeleted, report.CommentsDeleted)
+	return report, nil
+}
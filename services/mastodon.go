@@ -0,0 +1,270 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	SourceMastodon = "mastodon" // Value of TrendingPost.Source for items fetched from Mastodon
+
+	mastodonTitleLength = 200 // Maximum length of the title derived from a status' content
+)
+
+// MastodonSource is the Source reading the trends and hashtag timelines of a Mastodon instance.
+//
+// It fetches the trending statuses (/api/v1/trends/statuses), the trending hashtags (/api/v1/trends/tags)
+// and the public timeline of every configured hashtag (/api/v1/timelines/tag/{hashtag}).
+// Statuses are fetched as posts; hashtags are not statuses, so they are fetched as tags by FetchTags.
+// Mastodon has no downvotes, so favourites and boosts are both counted as upvotes.
+type MastodonSource struct {
+	instanceURL string       // Base URL of the instance (e.g., https://mastodon.social)
+	host        string       // Host of the instance, used to qualify IDs
+	hashtags    []string     // Hashtags whose timelines are fetched, without the leading #
+	limit       int          // Number of items requested from each endpoint
+	httpClient  *http.Client // HTTP client used to send requests
+}
+
+// NewMastodonSource creates a MastodonSource for the given instance and hashtags.
+// A nil httpClient defaults to a client with a 10 second timeout.
+func NewMastodonSource(instanceURL string, hashtags []string, limit int, httpClient *http.Client) (*MastodonSource, error) {
+	parsed, err := url.Parse(instanceURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Mastodon instance URL %q", instanceURL)
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	// Normalize the hashtags to bare names
+	var tags []string
+	for _, tag := range hashtags {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return &MastodonSource{
+		instanceURL: strings.TrimRight(instanceURL, "/"),
+		host:        parsed.Host,
+		hashtags:    tags,
+		limit:       limit,
+		httpClient:  httpClient,
+	}, nil
+}
+
+// Name identifies the source as "mastodon:<instance host>".
+func (s *MastodonSource) Name() string {
+	return SourceMastodon + ":" + s.host
+}
+
+// Fetch retrieves the trending statuses and the configured hashtag timelines.
+// An endpoint that fails is reported as a skipped item; the fetch only fails if every endpoint fails.
+func (s *MastodonSource) Fetch(ctx context.Context) (*ListingResult, error) {
+	result := &ListingResult{}
+	seen := make(map[string]bool) // A status can be both trending and in a hashtag timeline
+	failures := 0
+
+	// addStatuses normalizes the statuses of one endpoint, ranked by their position in it
+	addStatuses := func(listing string, community string, statuses []models.MastodonStatus) {
+		for i, status := range statuses {
+			post := s.normalizeStatus(status)
+			if seen[post.ID] {
+				continue
+			}
+			seen[post.ID] = true
+			post.Rank = i + 1
+			post.Listing = listing
+			post.Community = community
+			result.Count++
+			result.Posts = append(result.Posts, post)
+		}
+	}
+
+	// recordFailure reports an endpoint that could not be fetched
+	recordFailure := func(index int, endpoint string, err error) {
+		failures++
+		result.Skipped++
+		result.Errors = append(result.Errors, &ChildError{Index: index, Kind: SourceMastodon, Err: fmt.Errorf("%s: %v", endpoint, err)})
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(s.limit))
+
+	var statuses []models.MastodonStatus
+	if err := s.getJSON(ctx, "/api/v1/trends/statuses", query, &statuses); err != nil {
+		recordFailure(0, "trends/statuses", err)
+	} else {
+		addStatuses("trends", "", statuses)
+	}
+
+	for i, hashtag := range s.hashtags {
+		var timeline []models.MastodonStatus
+		if err := s.getJSON(ctx, "/api/v1/timelines/tag/"+url.PathEscape(hashtag), query, &timeline); err != nil {
+			recordFailure(1+i, "timelines/tag/"+hashtag, err)
+			continue
+		}
+		addStatuses("tag:"+hashtag, "#"+hashtag, timeline)
+	}
+
+	if failures == 1+len(s.hashtags) {
+		return nil, fmt.Errorf("failed to fetch any Mastodon endpoint of %s: %v", s.host, result.Errors[0])
+	}
+	return result, nil
+}
+
+// getJSON sends a GET request for the given API path and decodes the JSON response into target.
+func (s *MastodonSource) getJSON(ctx context.Context, path string, query url.Values, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.instanceURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Mastodon request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Mastodon request: %v", err)
+	}
+	defer res.Body.Close() // Ensure response body is closed
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Mastodon response: %v", err)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal Mastodon response: %v", err)
+	}
+	return nil
+}
+
+// normalizeStatus turns a Mastodon status into a trending post. Boosts are replaced by the boosted status.
+// The ID is qualified with the instance host, since status IDs are only unique within an instance.
+func (s *MastodonSource) normalizeStatus(status models.MastodonStatus) models.TrendingPost {
+	if status.Reblog != nil {
+		status = *status.Reblog
+	}
+
+	text := StripHTML(status.Content)
+	title := text
+	if status.SpoilerText != "" {
+		title = status.SpoilerText // Keep content behind a content warning out of the title
+	}
+
+	// The status links to its own page unless it carries a preview card
+	link := status.URL
+	domain := ""
+	if status.Card != nil && status.Card.URL != "" {
+		link = status.Card.URL
+		if parsed, err := url.Parse(link); err == nil {
+			domain = parsed.Hostname()
+		}
+	}
+
+	return models.TrendingPost{
+		ID:          s.host + ":" + status.ID,
+		Source:      SourceMastodon,
+		Name:        truncateText(title, mastodonTitleLength),
+		VolumeUp:    status.FavouritesCount + status.ReblogsCount,
+		Permalink:   firstNonEmpty(status.URL, status.URI),
+		URL:         firstNonEmpty(link, status.URI),
+		Author:      status.Account.Acct,
+		CreatedAt:   status.CreatedAt.UTC(),
+		NumComments: status.RepliesCount,
+		Score:       status.FavouritesCount + status.ReblogsCount,
+		Over18:      status.Sensitive,
+		Spoiler:     status.SpoilerText != "",
+		Domain:      domain,
+		IsSelf:      domain == "",
+		Selftext:    text,
+	}
+}
+
+// FetchTags retrieves the trending hashtags of the instance.
+func (s *MastodonSource) FetchTags(ctx context.Context) ([]models.TrendingTag, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(s.limit))
+
+	var tags []models.MastodonTag
+	if err := s.getJSON(ctx, "/api/v1/trends/tags", query, &tags); err != nil {
+		return nil, fmt.Errorf("failed to fetch trending tags of %s: %v", s.host, err)
+	}
+
+	trending := make([]models.TrendingTag, len(tags))
+	for i, tag := range tags {
+		trending[i] = s.normalizeTag(tag)
+		trending[i].Rank = i + 1
+	}
+	return trending, nil
+}
+
+// normalizeTag turns a trending hashtag into a trending tag counting the hashtag's uses of the most recent day.
+func (s *MastodonSource) normalizeTag(tag models.MastodonTag) models.TrendingTag {
+	uses, accounts := 0, 0
+	if len(tag.History) > 0 {
+		uses, _ = strconv.Atoi(tag.History[0].Uses)
+		accounts, _ = strconv.Atoi(tag.History[0].Accounts)
+	}
+
+	return models.TrendingTag{
+		ID:       s.host + ":" + strings.ToLower(tag.Name),
+		Instance: s.host,
+		Name:     tag.Name,
+		URL:      tag.URL,
+		Uses:     uses,
+		Accounts: accounts,
+	}
+}
+
+// StoreTrendingTags stores or updates the given trending tags in the tags collection with a single unordered
+// BulkWrite. It returns an error if the write fails.
+func StoreTrendingTags(ctx context.Context, collection *mongo.Collection, tags []models.TrendingTag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(tags))
+	for _, tag := range tags {
+		tag.InsertedAt = time.Now()
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": tag.ID}).
+			SetReplacement(tag).
+			SetUpsert(true))
+	}
+
+	if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to upsert trending tags into MongoDB: %v", err)
+	}
+	return nil
+}
+
+// truncateText shortens text to at most limit runes, cutting at a word boundary and appending an ellipsis.
+func truncateText(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ") // Collapse newlines and repeated spaces
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)[:limit]
+	cut := string(runes)
+	if space := strings.LastIndex(cut, " "); space > limit/2 {
+		cut = cut[:space]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMastodonSourceFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/trends/statuses", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": "1", "content": "<p>Hello <b>fediverse</b></p>", "url": "https://example.social/@a/1",
+			"account": {"acct": "a"}, "favourites_count": 3, "reblogs_count": 2, "replies_count": 1}]`)
+	})
+	mux.HandleFunc("/api/v1/trends/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name": "GoLang", "url": "https://example.social/tags/golang",
+			"history": [{"day": "1700000000", "uses": "42", "accounts": "17"}]}]`)
+	})
+	mux.HandleFunc("/api/v1/timelines/tag/golang", func(w http.ResponseWriter, r *http.Request) {
+		// The trending status again, boosted, followed by a status of its own
+		fmt.Fprint(w, `[{"id": "9", "reblog": {"id": "1", "content": "<p>Hello</p>", "account": {"acct": "a"}}},
+			{"id": "2", "content": "<p>Go</p>", "spoiler_text": "CW", "account": {"acct": "b@other.social"}}]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source, err := NewMastodonSource(server.URL, []string{"#golang"}, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hashtags are fetched as tags, never as posts
+	if len(result.Posts) != 2 || result.Skipped != 0 {
+		t.Fatalf("got %d posts with %d skipped, want 2 statuses", len(result.Posts), result.Skipped)
+	}
+	for _, post := range result.Posts {
+		if strings.HasPrefix(post.Name, "#") {
+			t.Fatalf("hashtag fetched as post %+v", post)
+		}
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	status := result.Posts[0]
+	if status.ID != host+":1" || status.Name != "Hello fediverse" || status.Score != 5 || status.NumComments != 1 ||
+		status.Listing != "trends" || status.Rank != 1 {
+		t.Fatalf("status normalized as %+v", status)
+	}
+	if second := result.Posts[1]; second.ID != host+":2" || second.Name != "CW" || second.Community != "#golang" || second.Rank != 2 {
+		t.Fatalf("timeline status normalized as %+v", second)
+	}
+
+	tags, err := source.FetchTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Fatalf("got %d tags, want 1", len(tags))
+	}
+	tag := tags[0]
+	if tag.ID != host+":golang" || tag.Instance != host || tag.Name != "GoLang" || tag.Rank != 1 ||
+		tag.Uses != 42 || tag.Accounts != 17 {
+		t.Fatalf("tag normalized as %+v", tag)
+	}
+}
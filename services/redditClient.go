@@ -0,0 +1,122 @@
+package services
+
+import (
+	"backend/config"
+	"net/http"
+	"time"
+)
+
+// RedditCredentials holds the OAuth credentials used to obtain Reddit access tokens.
+type RedditCredentials struct {
+	GrantType    GrantType // The OAuth grant used to obtain tokens
+	ClientID     string    // The app's client ID
+	ClientSecret string    // The app's client secret, unused by the installed client grant
+	Username     string    // The account username, only used by the password grant
+	Password     string    // The account password, only used by the password grant
+	DeviceID     string    // The device ID, only used by the installed client grant
+}
+
+// RedditClient is a client for the Reddit API.
+//
+// It owns the cached access token and the rate-limit aware HTTP client, so a single instance should be
+// shared by all callers. Its endpoints, user agent, HTTP client, credentials and clock are configurable
+// through RedditClientOption values, which makes it possible to run it against a local stub server.
+type RedditClient struct {
+	authURL     string             // URL of the access token endpoint
+	apiURL      string             // Base URL of the OAuth API
+	userAgent   string             // User agent sent with every request
+	credentials RedditCredentials  // Credentials used to obtain access tokens
+	httpClient  *http.Client       // Underlying HTTP client
+	now         func() time.Time   // Clock used for token expiry and rate-limit waits
+	http        *RateLimitedClient // Rate-limit aware client wrapping httpClient
+	tokens      *TokenSource       // Cache of the access token
+}
+
+// RedditClientOption configures a RedditClient.
+type RedditClientOption func(*RedditClient)
+
+// WithBaseURLs overrides the access token endpoint and the base URL of the OAuth API.
+func WithBaseURLs(authURL string, apiURL string) RedditClientOption {
+	return func(c *RedditClient) {
+		c.authURL = authURL
+		c.apiURL = apiURL
+	}
+}
+
+// WithUserAgent overrides the user agent sent with every request.
+func WithUserAgent(userAgent string) RedditClientOption {
+	return func(c *RedditClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHTTPClient overrides the HTTP client used to send requests.
+func WithHTTPClient(httpClient *http.Client) RedditClientOption {
+	return func(c *RedditClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithCredentials sets the credentials used to obtain access tokens.
+func WithCredentials(credentials RedditCredentials) RedditClientOption {
+	return func(c *RedditClient) {
+		c.credentials = credentials
+	}
+}
+
+// WithClock overrides the clock used for token expiry and rate-limit waits.
+func WithClock(now func() time.Time) RedditClientOption {
+	return func(c *RedditClient) {
+		c.now = now
+	}
+}
+
+// NewRedditClient creates a RedditClient talking to the public Reddit endpoints, customized by the given options.
+func NewRedditClient(opts ...RedditClientOption) *RedditClient {
+	c := &RedditClient{
+		authURL:     RedditAuthURL,
+		apiURL:      RedditAPIURL,
+		userAgent:   RedditUserAgent,
+		credentials: RedditCredentials{GrantType: GrantPassword},
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+	}
+
+	// Apply the options before building the components that depend on them
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.http = NewRateLimitedClient(c.httpClient, config.GetEnvInt("REDDIT_RATELIMIT_RESERVE", DefaultRateLimitReserve))
+	c.http.Limiter.now = c.now
+	c.tokens = NewTokenSource(c.FetchAccessToken)
+	c.tokens.now = c.now
+	return c
+}
+
+// NewRedditClientFromEnv creates a RedditClient with the credentials configured in the environment.
+//
+// The grant type is read from REDDIT_GRANT_TYPE, the credentials from REDDIT_CLIENT_ID, REDDIT_CLIENT_SECRET,
+// REDDIT_USERNAME, REDDIT_PASSWORD and REDDIT_DEVICE_ID. Additional options are applied after the credentials.
+func NewRedditClientFromEnv(opts ...RedditClientOption) (*RedditClient, error) {
+	grantType, err := ParseGrantType(config.GetEnv("REDDIT_GRANT_TYPE", string(GrantPassword)))
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := RedditCredentials{
+		GrantType:    grantType,
+		ClientID:     config.GetEnv("REDDIT_CLIENT_ID", ""),
+		ClientSecret: config.GetEnv("REDDIT_CLIENT_SECRET", ""),
+		Username:     config.GetEnv("REDDIT_USERNAME", ""),
+		Password:     config.GetEnv("REDDIT_PASSWORD", ""),
+		DeviceID:     config.GetEnv("REDDIT_DEVICE_ID", DefaultDeviceID),
+	}
+
+	return NewRedditClient(append([]RedditClientOption{WithCredentials(credentials)}, opts...)...), nil
+}
+
+// RateLimit returns the current Reddit rate-limit budget for observability.
+func (c *RedditClient) RateLimit() RateLimitBudget {
+	return c.http.Limiter.Budget()
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStubClient starts a stub Reddit serving access tokens at /auth and the OAuth API handler at /api, and
+// returns a client talking to it with the number of tokens issued so far. Tokens are numbered "token-1",
+// "token-2", and so on, and last an hour.
+func newStubClient(t *testing.T, api http.HandlerFunc, opts ...RedditClientOption) (*RedditClient, *atomic.Int32) {
+	t.Helper()
+	issued := &atomic.Int32{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != string(GrantClientCredentials) {
+			http.Error(w, `{"error": "unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+		n := issued.Add(1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 3600}`, n)
+	})
+	mux.Handle("/api/", http.StripPrefix("/api", api))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	opts = append([]RedditClientOption{
+		WithBaseURLs(server.URL+"/auth", server.URL+"/api"),
+		WithCredentials(RedditCredentials{GrantType: GrantClientCredentials, ClientID: "id", ClientSecret: "secret"}),
+	}, opts...)
+	return NewRedditClient(opts...), issued
+}
+
+// listingJSON returns a listing page holding a post per ID, followed by the given cursor.
+func listingJSON(after string, ids ...string) string {
+	children := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		children[i] = map[string]any{"kind": "t3", "data": map[string]any{"id": id, "title": "Post " + id, "score": 10}}
+	}
+	body, _ := json.Marshal(map[string]any{"kind": "Listing", "data": map[string]any{"after": after, "children": children}})
+	return string(body)
+}
+
+// bearer returns the token of the request's Authorization header.
+func bearer(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func TestRedditClientCachesToken(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	var tokens []string
+	client, issued := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, bearer(r))
+		mu.Unlock()
+		fmt.Fprint(w, listingJSON("", "a"))
+	}, WithClock(clock))
+
+	opts := ListingOptions{Sort: SortHot, MaxPosts: 1}
+	for i := 0; i < 3; i++ {
+		if _, err := client.FetchTrendingPosts(context.Background(), "golang", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("issued %d tokens for 3 requests, want 1", issued.Load())
+	}
+
+	// The token is refreshed ahead of its expiry
+	mu.Lock()
+	now = now.Add(time.Hour - TokenRefreshLeeway)
+	mu.Unlock()
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", opts); err != nil {
+		t.Fatal(err)
+	}
+	if issued.Load() != 2 || tokens[3] != "token-2" {
+		t.Fatalf("issued %d tokens and sent %v, want a second token once the first expires", issued.Load(), tokens)
+	}
+}
+
+func TestRedditClientRefreshesRejectedToken(t *testing.T) {
+	client, issued := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The first token is revoked
+		if bearer(r) == "token-1" {
+			http.Error(w, `{"message": "Unauthorized", "error": 401}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, listingJSON("", "a"))
+	})
+
+	result, err := client.FetchTrendingPosts(context.Background(), "golang", ListingOptions{MaxPosts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Posts) != 1 || issued.Load() != 2 {
+		t.Fatalf("got %d posts with %d tokens issued, want 1 post after refreshing the token", len(result.Posts), issued.Load())
+	}
+}
+
+func TestRedditClientTracksRateLimit(t *testing.T) {
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Used", "4")
+		w.Header().Set("X-Ratelimit-Remaining", "596.0")
+		w.Header().Set("X-Ratelimit-Reset", "120")
+		fmt.Fprint(w, listingJSON("", "a"))
+	})
+
+	if client.RateLimit().Known {
+		t.Fatal("budget is known before any request")
+	}
+	before := time.Now()
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", ListingOptions{MaxPosts: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := client.RateLimit()
+	if !budget.Known || budget.Used != 4 || budget.Remaining != 596 {
+		t.Fatalf("budget is %+v, want 4 used and 596 remaining", budget)
+	}
+	if reset := budget.ResetAt.Sub(before); reset < 120*time.Second || reset > 125*time.Second {
+		t.Fatalf("budget resets in %s, want 120s", reset)
+	}
+}
+
+func TestRedditClientWaitsForRateLimitReset(t *testing.T) {
+	var requests atomic.Int32
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The first response exhausts the budget until the window resets shortly after
+		if requests.Add(1) == 1 {
+			w.Header().Set("X-Ratelimit-Remaining", "0")
+			w.Header().Set("X-Ratelimit-Reset", "0.2")
+		} else {
+			w.Header().Set("X-Ratelimit-Remaining", "600")
+			w.Header().Set("X-Ratelimit-Reset", "600")
+		}
+		fmt.Fprint(w, listingJSON("", "a"))
+	})
+
+	opts := ListingOptions{MaxPosts: 1}
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", opts); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", opts); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Fatalf("second request was sent after %s, want it to wait for the window to reset", waited)
+	}
+
+	// A cancelled wait returns the context's error
+	client.http.Limiter.Update(http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"600"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchTrendingPosts(ctx, "golang", opts); err == nil {
+		t.Fatal("request with an exhausted budget succeeded before the window reset")
+	}
+}
+
+func TestRedditClientRetriesTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, listingJSON("", "a"))
+	})
+	client.http.BaseBackoff = time.Millisecond
+
+	result, err := client.FetchTrendingPosts(context.Background(), "golang", ListingOptions{MaxPosts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Posts) != 1 || requests.Load() != 3 {
+		t.Fatalf("got %d posts after %d requests, want 1 post after 2 retries", len(result.Posts), requests.Load())
+	}
+
+	// Once the retries are exhausted the status is returned
+	requests.Store(-10)
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", ListingOptions{MaxPosts: 1}); err == nil {
+		t.Fatal("request succeeded although every attempt was throttled")
+	}
+	if got := requests.Load(); got != -10+int32(DefaultMaxRetries)+1 {
+		t.Fatalf("sent %d attempts, want %d", got+10, DefaultMaxRetries+1)
+	}
+}
+
+func TestRedditClientHonorsLongRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "120")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, listingJSON("", "a"))
+	})
+	var waits []time.Duration
+	client.http.Limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	if _, err := client.FetchTrendingPosts(context.Background(), "golang", ListingOptions{MaxPosts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(waits) != 1 || waits[0] != 120*time.Second {
+		t.Fatalf("waited %v before retrying, want the 2m0s of Retry-After beyond MaxBackoff %s", waits, client.http.MaxBackoff)
+	}
+}
+
+func TestRedditClientFollowsAfter(t *testing.T) {
+	// A listing of seven posts, paginated like Reddit: up to limit posts after the cursor, then the next cursor
+	ids := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var queries []string
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/r/golang+rust/top" {
+			http.NotFound(w, r)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+
+		start := 0
+		if after := r.URL.Query().Get("after"); after != "" {
+			start = slices.Index(ids, strings.TrimPrefix(after, "t3_")) + 1
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page := ids[start:min(start+limit, len(ids))]
+		fmt.Fprint(w, listingJSON("t3_"+page[len(page)-1], page...))
+	})
+
+	opts := ListingOptions{Sort: SortTop, Window: WindowWeek, PageSize: 2, MaxPosts: 5}
+	result, err := client.FetchTrendingPosts(context.Background(), "r/golang+rust", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"count=0&limit=2&t=week",
+		"after=t3_b&count=2&limit=2&t=week",
+		"after=t3_d&count=4&limit=1&t=week",
+	}
+	if strings.Join(queries, " ") != strings.Join(want, " ") {
+		t.Fatalf("sent queries %v, want %v", queries, want)
+	}
+	if len(result.Posts) != 5 || result.After != "t3_e" {
+		t.Fatalf("got %d posts with cursor %q, want 5 posts with cursor t3_e", len(result.Posts), result.After)
+	}
+	for i, post := range result.Posts {
+		if post.Rank != i+1 {
+			t.Fatalf("post %s has rank %d, want %d", post.ID, post.Rank, i+1)
+		}
+	}
+}
+
+func TestRedditClientRejectsInvalidSubreddit(t *testing.T) {
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	})
+
+	for _, subreddit := range []string{"x/../../api/v1/me?", "a", "golang/new", "golang+"} {
+		if _, err := client.FetchTrendingPosts(context.Background(), subreddit, ListingOptions{MaxPosts: 1}); err == nil {
+			t.Errorf("fetching %q succeeded, want an invalid name error", subreddit)
+		}
+	}
+}
+
+func TestRedditClientRejectsInvalidPostID(t *testing.T) {
+	client, _ := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	})
+
+	for _, postID := range []string{"", "../api/v1/me", "abc?limit=1", "t3_abc", "ABC"} {
+		if _, err := client.FetchPostComments(context.Background(), postID, 10); err == nil {
+			t.Errorf("fetching comments of %q succeeded, want an invalid ID error", postID)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jonreiter/govader"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// postIDPattern matches a Reddit post ID, a base36 number without the "t3_" kind prefix.
+var postIDPattern = regexp.MustCompile(`^[0-9a-z]{1,13}$`)
+
+// FetchPostComments retrieves up to limit comments of a post, sorted by Reddit's "top" order.
+// The comment tree is flattened depth-first; every comment keeps its depth and the fullname of its parent.
+// "more" placeholders and malformed comments are skipped.
+// Since the ID becomes part of the path of an authenticated API request, anything but a base36 ID is rejected.
+func (c *RedditClient) FetchPostComments(ctx context.Context, postID string, limit int) ([]models.RedditComment, error) {
+	if !postIDPattern.MatchString(postID) {
+		return nil, fmt.Errorf("invalid Reddit post ID %q", postID)
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("sort", "top")
+	query.Set("raw_json", "1") // Return the body without HTML entity escaping
+
+	body, status, err := c.get(ctx, "/comments/"+postID, query)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("reddit API returned status %d", status)
+	}
+
+	// The response holds two listings: the post itself, then the comment tree
+	var listings []models.RedditListing
+	if err := json.Unmarshal(body, &listings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Reddit comments: %v", err)
+	}
+	if len(listings) < 2 {
+		return nil, fmt.Errorf("unexpected Reddit comments response format")
+	}
+
+	var comments []models.RedditComment
+	flattenComments(postID, listings[1].Data.Children, &comments)
+
+	// Reddit's limit bounds the tree it loads, not the number of comments, so enforce it here
+	if limit > 0 && len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+// flattenComments appends the comments of a tree level, each followed by its replies, to comments.
+func flattenComments(postID string, children []models.RedditThing, comments *[]models.RedditComment) {
+	for _, child := range children {
+		if child.Kind != "t1" {
+			continue // Skip "more" placeholders
+		}
+
+		var data models.RedditCommentData
+		if err := json.Unmarshal(child.Data, &data); err != nil || data.ID == "" {
+			continue // Skip malformed comments
+		}
+
+		*comments = append(*comments, models.RedditComment{
+			ID:        data.ID,
+			PostID:    postID,
+			ParentID:  data.ParentID,
+			Author:    data.Author,
+			Body:      data.Body,
+			Score:     int(data.Score),
+			Depth:     data.Depth,
+			CreatedAt: unixSeconds(data.CreatedUTC),
+		})
+
+		// Replies are an empty string when there are none, otherwise a nested listing
+		var replies models.RedditListing
+		if err := json.Unmarshal(data.Replies, &replies); err == nil {
+			flattenComments(postID, replies.Data.Children, comments)
+		}
+	}
+}
+
+// StoreRedditComments stores or updates the comments of a post in the comments collection with a single unordered
+// BulkWrite. It performs sentiment analysis on every comment body, then stores the aggregate comment sentiment
+// on the post, keyed by its source and platform ID, in the post repository. It returns the aggregate or an error
+// if a write fails.
+func StoreRedditComments(ctx context.Context, commentsCollection *mongo.Collection, posts repository.PostRepository, source string, postID string, comments []models.RedditComment) (*models.CommentSentiment, error) {
+	analyzer := govader.NewSentimentIntensityAnalyzer() // Initialize the sentiment analyzer
+
+	aggregate := &models.CommentSentiment{UpdatedAt: time.Now()}
+	total := 0.0
+	writes := make([]mongo.WriteModel, 0, len(comments))
+
+	for _, comment := range comments {
+		sentiment := analyzer.PolarityScores(comment.Body) // Analyze sentiment of the comment body
+		comment.SentimentScore = sentiment.Compound
+		comment.Sentiment = SentimentLabel(sentiment.Compound)
+		comment.InsertedAt = time.Now()
+
+		// Count the comment in the aggregate
+		total += sentiment.Compound
+		aggregate.Count++
+		switch comment.Sentiment {
+		case "positive":
+			aggregate.Positive++
+		case "negative":
+			aggregate.Negative++
+		default:
+			aggregate.Neutral++
+		}
+
+		// Replace the stored comment, inserting it if it is new
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": comment.ID}).
+			SetReplacement(comment).
+			SetUpsert(true))
+	}
+
+	if len(writes) > 0 {
+		_, err := commentsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert Reddit comments into MongoDB: %v", err)
+		}
+	}
+
+	if aggregate.Count > 0 {
+		aggregate.Average = total / float64(aggregate.Count)
+	}
+	aggregate.Label = SentimentLabel(aggregate.Average)
+
+	// Attach the aggregate to the post
+	if err := posts.SetCommentSentiment(ctx, models.PostKey(source, postID), aggregate); err != nil {
+		return nil, err
+	}
+
+	return aggregate, nil
+}
+
+// RetrieveRedditComments retrieves the stored comments of a post, ordered by depth and then by score.
+// It returns a slice of RedditComment models or an error if the retrieval fails.
+func RetrieveRedditComments(ctx context.Context, collection *mongo.Collection, postID string) ([]models.RedditComment, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "depth", Value: 1}, {Key: "score", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"post_id": postID}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Reddit comments from MongoDB: %v", err)
+	}
+	defer func(cursor *mongo.Cursor, ctx context.Context) {
+		err := cursor.Close(ctx) // Ensure the cursor is closed after usage
+		if err != nil {
+			log.Printf("Failed to close cursor: %v", err)
+		}
+	}(cursor, ctx)
+
+	var comments []models.RedditComment
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, fmt.Errorf("failed to decode Reddit comments from cursor: %v", err)
+	}
+
+	return comments, nil // Return the slice of retrieved comments
+}
+
+// DeleteRedditComments deletes the stored comments of the given posts and returns how many there were.
+// With dryRun set the comments are only counted.
+func DeleteRedditComments(ctx context.Context, collection *mongo.Collection, postIDs []string, dryRun bool) (int, error) {
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+
+	filter := bson.M{"post_id": bson.M{"$in": postIDs}}
+	if dryRun {
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count Reddit comments in MongoDB: %v", err)
+		}
+		return int(count), nil
+	}
+
+	res, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete Reddit comments from MongoDB: %v", err)
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"backend/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListingResult represents the outcome of decoding a Reddit listing.
+// Children that could not be decoded are skipped and reported in Errors instead of failing the whole listing.
+type ListingResult struct {
+	Posts   []models.TrendingPost // The posts successfully decoded from the listing
+	Count   int                   // Number of children in the listing, including skipped ones
+	Skipped int                   // Number of children that were skipped
+	Errors  []error               // One ChildError per skipped child
+	After   string                // Cursor of the next page, empty when there are no more pages
+	Before  string                // Cursor of the previous page
+}
+
+// ChildError describes why a single child of a listing was skipped.
+type ChildError struct {
+	Index int    // Position of the child in the listing
+	Kind  string // Kind of the child (e.g., t3)
+	Err   error  // The underlying decoding error
+}
+
+// Error implements the error interface.
+func (e *ChildError) Error() string {
+	return fmt.Sprintf("listing child %d (kind %q): %v", e.Index, e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *ChildError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeRedditListing decodes the body of a Reddit listing response into trending posts.
+// Only the envelope must be well-formed; malformed or unsupported children are skipped and reported.
+func DecodeRedditListing(body []byte) (*ListingResult, error) {
+	var listing models.RedditListing
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Reddit listing: %v", err)
+	}
+
+	if listing.Kind != "Listing" {
+		return nil, fmt.Errorf("unexpected Reddit API response kind %q", listing.Kind)
+	}
+
+	result := &ListingResult{
+		Count:  len(listing.Data.Children),
+		After:  listing.Data.After,
+		Before: listing.Data.Before,
+	}
+
+	for i, child := range listing.Data.Children {
+		post, err := decodeRedditLink(child)
+		if err != nil {
+			// Record the failure and move on to the next child
+			result.Skipped++
+			result.Errors = append(result.Errors, &ChildError{Index: i, Kind: child.Kind, Err: err})
+			continue
+		}
+		post.Rank = i + 1 // Rank relative to this page; callers offset it when paginating
+		result.Posts = append(result.Posts, post)
+	}
+
+	return result, nil
+}
+
+// decodeRedditLink decodes a single t3 thing into a trending post.
+func decodeRedditLink(child models.RedditThing) (models.TrendingPost, error) {
+	if child.Kind != "t3" {
+		return models.TrendingPost{}, fmt.Errorf("unsupported kind")
+	}
+
+	var link models.RedditLink
+	if err := json.Unmarshal(child.Data, &link); err != nil {
+		return models.TrendingPost{}, fmt.Errorf("failed to decode link data: %v", err)
+	}
+
+	if link.ID == "" {
+		return models.TrendingPost{}, fmt.Errorf("missing post id")
+	}
+
+	return models.TrendingPost{
+		ID:                link.ID,
+		Source:            SourceReddit,
+		Name:              link.Title,
+		VolumeUp:          int(link.Ups),
+		VolumeDown:        int(link.Downs),
+		Subreddit:         link.Subreddit,
+		SubredditPrefixed: link.SubredditNamePrefixed,
+		Permalink:         redditPermalink(link.Permalink),
+		URL:               link.URL,
+		Author:            link.Author,
+		CreatedAt:         unixSeconds(link.CreatedUTC),
+		NumComments:       int(link.NumComments),
+		UpvoteRatio:       link.UpvoteRatio,
+		Score:             int(link.Score),
+		Over18:            link.Over18,
+		Spoiler:           link.Spoiler,
+		Stickied:          link.Stickied,
+		FlairText:         link.LinkFlairText,
+		Domain:            link.Domain,
+		IsSelf:            link.IsSelf,
+		Selftext:          link.Selftext,
+		Thumbnail:         redditThumbnail(link.Thumbnail),
+	}, nil
+}
+
+// redditPermalink turns the relative permalink of a thing into an absolute URL.
+func redditPermalink(permalink string) string {
+	if permalink == "" || strings.HasPrefix(permalink, "http") {
+		return permalink
+	}
+	return RedditWebURL + permalink
+}
+
+// redditThumbnail returns the thumbnail URL, or an empty string when Reddit uses a placeholder keyword
+// such as "self", "default", "nsfw", "spoiler" or "image" instead of a URL.
+func redditThumbnail(thumbnail string) string {
+	if strings.HasPrefix(thumbnail, "http") {
+		return thumbnail
+	}
+	return ""
+}
+
+// unixSeconds converts a Unix timestamp in seconds, as used by Reddit, into a UTC time.
+// A zero timestamp yields the zero time.
+func unixSeconds(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRateLimitReserve = 5                      // Requests kept in reserve before waiting for the rate-limit window to reset
+	DefaultMaxRetries       = 4                      // Retries after the first attempt for 429, 5xx and network errors
+	DefaultBaseBackoff      = 500 * time.Millisecond // Backoff before the first retry, doubled on every further retry
+	DefaultMaxBackoff       = 30 * time.Second       // Upper bound for a single backoff
+)
+
+// RateLimitBudget is a snapshot of the Reddit rate-limit budget as last reported by the X-Ratelimit headers.
+type RateLimitBudget struct {
+	Known     bool      `json:"known"`      // Whether Reddit has reported a budget yet
+	Used      int       `json:"used"`       // Requests used in the current window
+	Remaining float64   `json:"remaining"`  // Requests remaining in the current window
+	ResetAt   time.Time `json:"reset_at"`   // When the current window ends and the budget is replenished
+	UpdatedAt time.Time `json:"updated_at"` // When the budget was last updated
+}
+
+// RateLimiter tracks the Reddit rate-limit budget and delays requests when it runs low.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	budget  RateLimitBudget
+	reserve float64                                    // Requests kept in reserve before waiting for the window to reset
+	now     func() time.Time                           // Clock used to compute waits
+	sleep   func(context.Context, time.Duration) error // Function used to wait
+}
+
+// NewRateLimiter creates a RateLimiter that starts waiting once fewer than reserve requests remain.
+func NewRateLimiter(reserve int) *RateLimiter {
+	return &RateLimiter{
+		reserve: float64(reserve),
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// Budget returns a snapshot of the current rate-limit budget.
+func (l *RateLimiter) Budget() RateLimitBudget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.budget
+}
+
+// Update records the budget reported by the X-Ratelimit headers of a response.
+// Responses without rate-limit headers leave the budget untouched.
+func (l *RateLimiter) Update(header http.Header) {
+	remaining, err := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	used, _ := strconv.Atoi(header.Get("X-Ratelimit-Used"))
+	resetIn, _ := strconv.ParseFloat(header.Get("X-Ratelimit-Reset"), 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.budget = RateLimitBudget{
+		Known:     true,
+		Used:      used,
+		Remaining: remaining,
+		ResetAt:   now.Add(time.Duration(resetIn * float64(time.Second))),
+		UpdatedAt: now,
+	}
+}
+
+// Wait blocks until a request can be sent without exhausting the budget, or until ctx is done.
+// Once the remaining budget falls below the reserve, it waits for the window to reset, however long that takes,
+// and checks the budget again, since another request may have used or updated it in the meantime.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		budget := l.budget
+		wait := budget.ResetAt.Sub(l.now())
+		if !budget.Known || budget.Remaining >= l.reserve || wait <= 0 {
+			if budget.Known {
+				// Count the request against the budget right away so concurrent callers see it before the response arrives
+				l.budget.Remaining--
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		log.Printf("Reddit rate-limit budget low (%.0f remaining), waiting %s", budget.Remaining, wait.Round(time.Millisecond))
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// RateLimitedClient is the HTTP client shared by all Reddit requests.
+// It waits for the rate-limit budget before sending, records the budget from every response,
+// and retries 429, 5xx and network errors with exponential backoff and jitter.
+type RateLimitedClient struct {
+	HTTP        *http.Client  // Underlying HTTP client
+	Limiter     *RateLimiter  // Tracker of the rate-limit budget
+	MaxRetries  int           // Retries after the first attempt
+	BaseBackoff time.Duration // Backoff before the first retry
+	MaxBackoff  time.Duration // Upper bound for a single backoff
+}
+
+// NewRateLimitedClient creates a RateLimitedClient wrapping httpClient with the default retry policy,
+// which starts waiting for the rate-limit window to reset once fewer than reserve requests remain.
+func NewRateLimitedClient(httpClient *http.Client, reserve int) *RateLimitedClient {
+	return &RateLimitedClient{
+		HTTP:        httpClient,
+		Limiter:     NewRateLimiter(reserve),
+		MaxRetries:  DefaultMaxRetries,
+		BaseBackoff: DefaultBaseBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+// Do sends the request built by newRequest and returns the response body and status code.
+// newRequest is called for every attempt with ctx, since a request body can only be sent once.
+// A 429 or 5xx status is only returned once the retries are exhausted. Cancelling ctx aborts the request
+// as well as any rate-limit wait or backoff.
+func (c *RateLimitedClient) Do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, int, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, 0, err
+		}
+
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		res, err := c.HTTP.Do(req)
+		if err != nil {
+			// A cancelled request is not retried
+			if ctx.Err() == nil && attempt < c.MaxRetries {
+				if err := c.backoff(ctx, attempt, 0); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+			return nil, 0, fmt.Errorf("failed to send Reddit request: %v", err)
+		}
+
+		c.Limiter.Update(res.Header)
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close() // Close the body before a possible retry
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read Reddit response: %v", err)
+		}
+
+		// Retry throttled and failed requests, honoring Retry-After when Reddit sends it
+		if (res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500) && attempt < c.MaxRetries {
+			retryAfter, _ := strconv.Atoi(res.Header.Get("Retry-After"))
+			log.Printf("Reddit request to %s returned status %d, retrying (attempt %d)", req.URL.Path, res.StatusCode, attempt+1)
+			if err := c.backoff(ctx, attempt, time.Duration(retryAfter)*time.Second); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		return body, res.StatusCode, nil
+	}
+}
+
+// backoff sleeps before the next retry using exponential backoff with full jitter.
+// A positive minimum, such as a Retry-After delay, is always honored, even beyond MaxBackoff.
+func (c *RateLimitedClient) backoff(ctx context.Context, attempt int, minimum time.Duration) error {
+	ceiling := c.BaseBackoff << attempt
+	if ceiling <= 0 || ceiling > c.MaxBackoff {
+		ceiling = c.MaxBackoff
+	}
+
+	// The jitter is capped at MaxBackoff, but a longer minimum still wins
+	wait := max(time.Duration(rand.Int63n(int64(ceiling)+1)), minimum)
+	return c.Limiter.sleep(ctx, wait)
+}
+
+// sleepContext waits for the given duration, returning early with ctx's error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
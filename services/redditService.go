@@ -3,16 +3,15 @@ package services
 import (
 	"backend/config"
 	"backend/models"
+	"backend/repository"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/jonreiter/govader"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,7 +19,10 @@ import (
 const (
 	RedditAuthURL   = "https://www.reddit.com/api/v1/access_token" // URL for Reddit authentication
 	RedditAPIURL    = "https://oauth.reddit.com"                   // Base URL for Reddit API
+	RedditWebURL    = "https://www.reddit.com"                     // Base URL of Reddit's website, used for permalinks
 	RedditUserAgent = "TrendlensBot/0.1 by Due_Effective477"       // User agent string for Reddit requests
+
+	RedditMaxPageSize = 100 // Maximum number of posts Reddit returns per listing page
 )
 
 // Token represents the structure of the access token received from Reddit.
@@ -28,210 +30,352 @@ type Token struct {
 	AccessToken string `json:"access_token"` // The access token for authenticating API requests
 	TokenType   string `json:"token_type"`   // The type of token (typically "bearer")
 	ExpiresIn   int    `json:"expires_in"`   // The expiration time of the token in seconds
+	Scope       string `json:"scope"`        // The scopes granted to the token
 }
 
-// FetchRedditAccessToken retrieves an access token from Reddit using the password grant type.
-// It returns the access token as a string or an error if the request fails.
-func FetchRedditAccessToken() (string, error) {
-	clientID := config.GetEnv("REDDIT_CLIENT_ID", "")
-	clientSecret := config.GetEnv("REDDIT_CLIENT_SECRET", "")
-	username := config.GetEnv("REDDIT_USERNAME", "")
-	password := config.GetEnv("REDDIT_PASSWORD", "")
+// authErrorResponse represents the error payload Reddit returns instead of a token when authentication fails.
+// Depending on the failure, "error" is either an OAuth error code (e.g., "invalid_grant") or an HTTP status number.
+type authErrorResponse struct {
+	Error            interface{} `json:"error"`             // OAuth error code or HTTP status
+	ErrorDescription string      `json:"error_description"` // Optional human-readable description
+	Message          string      `json:"message"`           // Optional message (e.g., "Unauthorized")
+}
 
-	// Prepare data for the POST request
-	data := url.Values{}
-	data.Set("grant_type", "password")
-	data.Set("username", username)
-	data.Set("password", password)
+// GrantType identifies the OAuth grant used to obtain Reddit access tokens.
+type GrantType string
 
-	req, err := http.NewRequest("POST", RedditAuthURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		return "", fmt.Errorf("failed to create auth request: %v", err)
+const (
+	GrantPassword          GrantType = "password"                                         // Script app acting on behalf of a user account
+	GrantClientCredentials GrantType = "client_credentials"                               // Application-only OAuth for confidential clients
+	GrantInstalledClient   GrantType = "https://oauth.reddit.com/grants/installed_client" // Application-only OAuth for installed apps without a secret
+)
+
+// DefaultDeviceID is the device ID sent with the installed client grant when REDDIT_DEVICE_ID is not set.
+// Reddit reserves this value for clients that do not want to be tracked.
+const DefaultDeviceID = "DO_NOT_TRACK_THIS_DEVICE"
+
+// ParseGrantType parses the grant type configured in REDDIT_GRANT_TYPE.
+// Besides the full grant names, "installed_client" is accepted as a shorthand. An empty value defaults to GrantPassword.
+func ParseGrantType(value string) (GrantType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", string(GrantPassword):
+		return GrantPassword, nil
+	case string(GrantClientCredentials):
+		return GrantClientCredentials, nil
+	case "installed_client", string(GrantInstalledClient):
+		return GrantInstalledClient, nil
+	default:
+		return "", fmt.Errorf("unsupported Reddit grant type %q", value)
 	}
+}
 
-	req.SetBasicAuth(clientID, clientSecret) // Set basic auth credentials
-	req.Header.Add("User-Agent", RedditUserAgent)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+// FetchAccessToken retrieves a new access token from Reddit using the grant type of the client's credentials.
+//
+// The password grant sends the username and password, the client_credentials grant only needs the client
+// credentials, and the installed client grant sends the device ID and an empty client secret.
+// It returns the token, including its lifetime, or an error if the request fails or Reddit answers with an error.
+// Callers should go through the client's cached token source rather than calling it directly.
+func (c *RedditClient) FetchAccessToken(ctx context.Context) (*Token, error) {
+	grantType := c.credentials.GrantType
+	if grantType == "" {
+		grantType = GrantPassword
+	}
+	clientID := c.credentials.ClientID
+	clientSecret := c.credentials.ClientSecret
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to execute auth request: %v", err)
+	// Prepare data for the POST request according to the grant type
+	data := url.Values{}
+	data.Set("grant_type", string(grantType))
+	switch grantType {
+	case GrantPassword:
+		data.Set("username", c.credentials.Username)
+		data.Set("password", c.credentials.Password)
+	case GrantInstalledClient:
+		deviceID := c.credentials.DeviceID
+		if deviceID == "" {
+			deviceID = DefaultDeviceID
+		}
+		data.Set("device_id", deviceID)
+		clientSecret = "" // Installed apps have no secret
 	}
-	defer res.Body.Close() // Ensure response body is closed
 
-	body, err := io.ReadAll(res.Body)
+	// Send the request through the rate-limit aware client, which retries throttled and failed attempts
+	body, status, err := c.http.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.authURL, strings.NewReader(data.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth request: %v", err)
+		}
+
+		req.SetBasicAuth(clientID, clientSecret) // Set basic auth credentials
+		req.Header.Add("User-Agent", c.userAgent)
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("failed to execute auth request: %v", err)
+	}
+
+	// Reddit reports authentication failures in the body, sometimes with a 200 status
+	var authErr authErrorResponse
+	if err := json.Unmarshal(body, &authErr); err == nil && authErr.Error != nil {
+		detail := authErr.ErrorDescription
+		if detail == "" {
+			detail = authErr.Message
+		}
+		return nil, fmt.Errorf("reddit auth failed with %v (status %d): %s", authErr.Error, status, detail)
 	}
 
 	var token Token
 	if err := json.Unmarshal(body, &token); err != nil {
-		return "", fmt.Errorf("failed to parse response body: %v", err)
+		return nil, fmt.Errorf("failed to parse response body: %v", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("auth response did not contain an access token (status %d)", status)
 	}
 
-	return token.AccessToken, nil // Return the access token
+	return &token, nil // Return the access token
 }
 
-// FetchRedditTrendingPosts retrieves the trending posts from Reddit's "hot" section.
-// It returns a slice of TrendingPost models or an error if the request fails.
-func FetchRedditTrendingPosts() ([]models.TrendingPost, error) {
-	accessToken, err := FetchRedditAccessToken() // Get access token
+// ListingOptions configures which Reddit listing is fetched, how many posts are collected and how they are paginated.
+type ListingOptions struct {
+	Sort     ListingSort // Sort order of the listing (hot, new, rising, top or controversial)
+	Window   TimeWindow  // Time window of the top and controversial listings, ignored for other sorts
+	PageSize int         // Number of posts requested per page (Reddit's "limit", capped at RedditMaxPageSize)
+	MaxPosts int         // Maximum number of posts to collect across all pages
+}
+
+// DefaultListingOptions returns the listing options configured through the REDDIT_SORT, REDDIT_TIME,
+// REDDIT_PAGE_SIZE and REDDIT_MAX_POSTS environment variables.
+// Invalid sort or time window values are logged and replaced by hot and day respectively.
+func DefaultListingOptions() ListingOptions {
+	sort, err := ParseListingSort(config.GetEnv("REDDIT_SORT", string(SortHot)))
 	if err != nil {
-		return nil, err
+		log.Printf("Invalid REDDIT_SORT, using %s: %v", SortHot, err)
+		sort = SortHot
 	}
 
-	req, err := http.NewRequest("GET", RedditAPIURL+"/r/all/hot", nil)
+	window, err := ParseTimeWindow(config.GetEnv("REDDIT_TIME", string(WindowDay)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Reddit API request: %v", err)
+		log.Printf("Invalid REDDIT_TIME, using %s: %v", WindowDay, err)
+		window = WindowDay
 	}
 
-	req.Header.Add("Authorization", "Bearer "+accessToken) // Set the authorization header
-	req.Header.Add("User-Agent", RedditUserAgent)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send Reddit API request: %v", err)
+	return ListingOptions{
+		Sort:     sort,
+		Window:   window,
+		PageSize: config.GetEnvInt("REDDIT_PAGE_SIZE", RedditMaxPageSize),
+		MaxPosts: config.GetEnvInt("REDDIT_MAX_POSTS", 100),
 	}
-	defer res.Body.Close() // Ensure response body is closed
+}
 
-	body, err := io.ReadAll(res.Body)
+// FetchTrendingPosts retrieves the trending posts from a listing of a subreddit, as selected by opts.Sort and opts.Window.
+// The subreddit may be a multireddit such as "golang+rust", with or without the "r/" prefix.
+// It follows the listing's "after" cursor until opts.MaxPosts posts have been collected or the listing is exhausted.
+// It returns the combined listing, where each post carries its absolute rank, or an error if the subreddit name
+// is invalid, a request fails or ctx is cancelled.
+func (c *RedditClient) FetchTrendingPosts(ctx context.Context, subreddit string, opts ListingOptions) (*ListingResult, error) {
+	subreddit, err := config.NormalizeSubreddit(subreddit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Reddit API response: %v", err)
+		return nil, err
 	}
 
-	var redditResponse map[string]interface{}
-	if err := json.Unmarshal(body, &redditResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Reddit API response: %v", err)
+	// Clamp the page size to what Reddit accepts
+	pageSize := opts.PageSize
+	if pageSize <= 0 || pageSize > RedditMaxPageSize {
+		pageSize = RedditMaxPageSize
 	}
 
-	data, ok := redditResponse["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unexpected Reddit API response format")
+	sort := opts.Sort
+	if sort == "" {
+		sort = SortHot // Default to the hot listing
 	}
+	path := "/r/" + subreddit + "/" + string(sort)
+	listing := ListingName(sort, opts.Window)
+
+	combined := &ListingResult{}
+	after := ""
+	for combined.Count < opts.MaxPosts {
+		// Never request more posts than are still needed
+		limit := pageSize
+		if remaining := opts.MaxPosts - combined.Count; remaining < limit {
+			limit = remaining
+		}
 
-	posts, ok := data["children"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unexpected Reddit API response format")
-	}
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("count", strconv.Itoa(combined.Count)) // Number of items already seen, used by Reddit for numbering
+		if after != "" {
+			query.Set("after", after)
+		}
+		if sort.UsesTimeWindow() && opts.Window != "" {
+			query.Set("t", string(opts.Window))
+		}
 
-	var trendingPosts []models.TrendingPost
-	for _, post := range posts {
-		postData, ok := post.(map[string]interface{})["data"].(map[string]interface{})
-		if !ok {
-			continue // Skip malformed post data
+		page, err := c.fetchListingPage(ctx, path, query)
+		if err != nil {
+			return nil, err
 		}
-		// Append the trending post to the slice
-		trendingPosts = append(trendingPosts, models.TrendingPost{
-			ID:         postData["id"].(string),
-			Name:       postData["title"].(string),
-			VolumeUp:   int(postData["ups"].(float64)),
-			VolumeDown: int(postData["downs"].(float64)),
-		})
+
+		// Offset the page-relative ranks by the number of children seen on previous pages
+		for _, post := range page.Posts {
+			post.Rank += combined.Count
+			post.Listing = listing
+			combined.Posts = append(combined.Posts, post)
+		}
+		combined.Errors = append(combined.Errors, page.Errors...)
+		combined.Skipped += page.Skipped
+		combined.Count += page.Count
+		combined.After = page.After
+		if combined.Before == "" {
+			combined.Before = page.Before
+		}
+
+		// Stop on an empty cursor or an empty page, both of which mean the listing is exhausted
+		if page.After == "" || page.Count == 0 {
+			break
+		}
+		after = page.After
 	}
-	return trendingPosts, nil // Return the slice of trending posts
-}
 
-// StoreRedditPosts stores or updates the trending posts in the MongoDB collection.
-// It performs sentiment analysis on the post titles and keeps track of voting history.
-func StoreRedditPosts(collection *mongo.Collection, posts []models.TrendingPost) error {
-	analyzer := govader.NewSentimentIntensityAnalyzer() // Initialize the sentiment analyzer
+	return combined, nil
+}
 
-	for _, post := range posts {
-		sentiment := analyzer.PolarityScores(post.Name) // Analyze sentiment of the post title
+// fetchListingPage retrieves and decodes a single page of a Reddit listing.
+func (c *RedditClient) fetchListingPage(ctx context.Context, path string, query url.Values) (*ListingResult, error) {
+	body, status, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
 
-		// Determine the sentiment label based on the compound score
-		sentimentLabel := "neutral"
-		if sentiment.Compound >= 0.05 {
-			sentimentLabel = "positive"
-		} else if sentiment.Compound <= -0.05 {
-			sentimentLabel = "negative"
-		}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("reddit API returned status %d", status)
+	}
 
-		filter := bson.M{"id": post.ID} // Create a filter for MongoDB query
-		var existingPost models.RedditPost
+	// Decode the listing; malformed children are skipped instead of failing the whole request
+	return DecodeRedditListing(body)
+}
 
-		err := collection.FindOne(context.Background(), filter).Decode(&existingPost)
-		if err != nil && err.Error() != "mongo: no documents in result" {
-			return fmt.Errorf("error fetching Reddit post from MongoDB: %v", err)
-		}
+// get performs an authenticated GET request against the OAuth API and returns the raw body and status code.
+// The path is joined to the API URL with its special characters escaped, so it cannot add a query string.
+// If Reddit rejects the cached access token, the token is refreshed and the request retried once.
+func (c *RedditClient) get(ctx context.Context, path string, query url.Values) ([]byte, int, error) {
+	endpoint, err := url.JoinPath(c.apiURL, path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build Reddit API URL: %v", err)
+	}
 
-		// Prepare the update for the MongoDB document
-		update := bson.M{
-			"$set": bson.M{
-				"title":       post.Name,
-				"upvotes":     post.VolumeUp,
-				"downvotes":   post.VolumeDown,
-				"subreddit":   "r/all",
-				"perma_link":  "https://reddit.com/r/all/comments/" + post.ID,
-				"url":         "https://reddit.com/r/all/comments/" + post.ID,
-				"inserted_at": time.Now(),
-				"sentiment":   sentimentLabel,
-			},
+	for attempt := 0; ; attempt++ {
+		accessToken, err := c.tokens.Token(ctx) // Get the cached access token
+		if err != nil {
+			return nil, 0, err
 		}
 
-		if existingPost.ID != "" {
-			// Track upvote history if the upvotes have changed
-			if existingPost.Upvotes != post.VolumeUp {
-				upvoteEntry := models.VoteHistoryEntry{
-					Value:     post.VolumeUp,
-					Timestamp: time.Now(),
-				}
-				update["$push"] = bson.M{"upvote_history": upvoteEntry}
+		// Send the request through the rate-limit aware client, which honors the rate-limit budget
+		body, status, err := c.http.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+query.Encode(), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Reddit API request: %v", err)
 			}
-			// Track downvote history if the downvotes have changed
-			if existingPost.Downvotes != post.VolumeDown {
-				downvoteEntry := models.VoteHistoryEntry{
-					Value:     post.VolumeDown,
-					Timestamp: time.Now(),
-				}
-				// If $push was already set, we need to merge the push for downvote_history
-				if pushData, ok := update["$push"]; ok {
-					pushData := pushData.(bson.M)
-					pushData["downvote_history"] = downvoteEntry
-					update["$push"] = pushData
-				} else {
-					update["$push"] = bson.M{"downvote_history": downvoteEntry}
-				}
-			}
-		}
 
-		// Use upsert to insert or update the document
-		upsert := true
-		opts := options.UpdateOptions{
-			Upsert: &upsert,
+			req.Header.Add("Authorization", "Bearer "+accessToken) // Set the authorization header
+			req.Header.Add("User-Agent", c.userAgent)
+			return req, nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to send Reddit API request: %v", err)
 		}
 
-		_, err = collection.UpdateOne(context.Background(), filter, update, &opts)
-		if err != nil {
-			return fmt.Errorf("failed to upsert Reddit post into MongoDB: %v", err)
+		// A 401 means the token was revoked or expired early; refresh it and retry once
+		if status == http.StatusUnauthorized && attempt == 0 {
+			c.tokens.Invalidate(accessToken)
+			continue
 		}
-	}
 
-	fmt.Println("Reddit posts stored or updated successfully: " + time.Now().Format("2006-01-02 15:04:05"))
-	return nil
+		return body, status, nil
+	}
 }
 
-// RetrieveRedditData retrieves all Reddit posts from the specified MongoDB collection.
-// It returns a slice of RedditPost models or an error if the retrieval fails.
-func RetrieveRedditData(collection *mongo.Collection) ([]models.RedditPost, error) {
-	filter := bson.M{} // Empty filter to retrieve all documents
+// StoreRedditPosts stores or updates the trending posts in the post repository.
+// It performs sentiment analysis on the post titles and, unless snapshots is nil, records a vote snapshot of every
+// post. All posts of the batch share the same InsertedAt timestamp, which is also the time of their snapshots.
+// A failure to record the snapshots is logged without failing the batch, since the posts are already stored.
+// It returns the counts of inserted, modified and failed posts, or an error if the batch could not be written.
+func StoreRedditPosts(ctx context.Context, repo repository.PostRepository, snapshots repository.SnapshotRepository, posts []models.TrendingPost) (*repository.UpsertResult, error) {
+	analyzer := govader.NewSentimentIntensityAnalyzer() // Initialize the sentiment analyzer
+	now := time.Now()
+
+	documents := make([]models.RedditPost, 0, len(posts))
+	for _, post := range posts {
+		sentiment := analyzer.PolarityScores(post.Name) // Analyze sentiment of the post title
+
+		documents = append(documents, models.RedditPost{
+			ID:                models.PostKey(post.Source, post.ID),
+			PostID:            post.ID,
+			Source:            post.Source,
+			Title:             post.Name,
+			Upvotes:           post.VolumeUp,
+			Downvotes:         post.VolumeDown,
+			Subreddit:         post.Subreddit,
+			SubredditPrefixed: post.SubredditPrefixed,
+			Community:         post.Community,
+			PermaLink:         post.Permalink,
+			URL:               post.URL,
+			Author:            post.Author,
+			CreatedAt:         post.CreatedAt,
+			NumComments:       post.NumComments,
+			UpvoteRatio:       post.UpvoteRatio,
+			Score:             post.Score,
+			Over18:            post.Over18,
+			Spoiler:           post.Spoiler,
+			Stickied:          post.Stickied,
+			FlairText:         post.FlairText,
+			Domain:            post.Domain,
+			IsSelf:            post.IsSelf,
+			Selftext:          post.Selftext,
+			Thumbnail:         post.Thumbnail,
+			Sentiment:         SentimentLabel(sentiment.Compound), // Determine the sentiment label based on the compound score
+			InsertedAt:        now,
+			Rank:              post.Rank,
+			Listing:           post.Listing,
+		})
+	}
 
-	cursor, err := collection.Find(context.Background(), filter)
+	result, err := repo.Upsert(ctx, documents)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve Reddit posts from MongoDB: %v", err)
+		return nil, fmt.Errorf("failed to store posts: %v", err)
 	}
-	defer func(cursor *mongo.Cursor, ctx context.Context) {
-		err := cursor.Close(ctx) // Ensure the cursor is closed after usage
-		if err != nil {
-			fmt.Println("Failed to close cursor: ", err)
+
+	log.Printf("Posts stored: %d inserted, %d modified, %d failed", result.Inserted, result.Modified, result.Failed)
+
+	// Record the votes observed by this scrape
+	if snapshots != nil {
+		observed := make([]models.VoteSnapshot, len(documents))
+		for i, document := range documents {
+			observed[i] = models.VoteSnapshot{
+				PostKey:     document.ID,
+				ObservedAt:  now,
+				Score:       document.Score,
+				Upvotes:     document.Upvotes,
+				NumComments: document.NumComments,
+				Rank:        document.Rank,
+				UpvoteRatio: document.UpvoteRatio,
+			}
 		}
-	}(cursor, context.Background())
+		if err := snapshots.Record(ctx, observed); err != nil {
+			log.Printf("Failed to record vote snapshots: %v", err)
+		}
+	}
 
-	var posts []models.RedditPost
-	if err = cursor.All(context.Background(), &posts); err != nil {
-		return nil, fmt.Errorf("failed to decode Reddit posts from cursor: %v", err)
+	return result, nil
+}
+
+// RetrieveRedditData retrieves all posts from the post repository.
+// It returns a slice of RedditPost models or an error if the retrieval fails.
+func RetrieveRedditData(ctx context.Context, repo repository.PostRepository) ([]models.RedditPost, error) {
+	posts, err := repo.Find(ctx, repository.PostQuery{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve posts: %v", err)
 	}
 
 	return posts, nil // Return the slice of retrieved posts
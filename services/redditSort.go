@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListingSort identifies one of the sort orders Reddit offers for subreddit listings.
+type ListingSort string
+
+const (
+	SortHot           ListingSort = "hot"           // Posts ranked by Reddit's hotness score
+	SortNew           ListingSort = "new"           // Most recent posts first
+	SortRising        ListingSort = "rising"        // Posts gaining traction quickly
+	SortTop           ListingSort = "top"           // Highest scored posts within a time window
+	SortControversial ListingSort = "controversial" // Most controversial posts within a time window
+)
+
+// TimeWindow identifies the time window ("t" parameter) applied to the top and controversial listings.
+type TimeWindow string
+
+const (
+	WindowHour  TimeWindow = "hour"
+	WindowDay   TimeWindow = "day"
+	WindowWeek  TimeWindow = "week"
+	WindowMonth TimeWindow = "month"
+	WindowYear  TimeWindow = "year"
+	WindowAll   TimeWindow = "all"
+)
+
+// ParseListingSort parses a sort name, case-insensitively. An empty name defaults to SortHot.
+func ParseListingSort(value string) (ListingSort, error) {
+	switch sort := ListingSort(strings.ToLower(strings.TrimSpace(value))); sort {
+	case "":
+		return SortHot, nil
+	case SortHot, SortNew, SortRising, SortTop, SortControversial:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("unsupported listing sort %q", value)
+	}
+}
+
+// ParseTimeWindow parses a time window name, case-insensitively. An empty name defaults to WindowDay,
+// which is also Reddit's default.
+func ParseTimeWindow(value string) (TimeWindow, error) {
+	switch window := TimeWindow(strings.ToLower(strings.TrimSpace(value))); window {
+	case "":
+		return WindowDay, nil
+	case WindowHour, WindowDay, WindowWeek, WindowMonth, WindowYear, WindowAll:
+		return window, nil
+	default:
+		return "", fmt.Errorf("unsupported time window %q", value)
+	}
+}
+
+// UsesTimeWindow reports whether the sort accepts a time window.
+func (s ListingSort) UsesTimeWindow() bool {
+	return s == SortTop || s == SortControversial
+}
+
+// ListingName returns the name under which posts observed in a listing are recorded,
+// e.g. "hot" or "top:week" for sorts that take a time window.
+func ListingName(sort ListingSort, window TimeWindow) string {
+	if sort == "" {
+		sort = SortHot
+	}
+	if sort.UsesTimeWindow() {
+		if window == "" {
+			window = WindowDay
+		}
+		return string(sort) + ":" + string(window)
+	}
+	return string(sort)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+)
+
+// SourceReddit is the value of TrendingPost.Source for posts fetched from Reddit.
+const SourceReddit = "reddit"
+
+// RedditSource is the Source fetching a listing of a single subreddit (or multireddit) through a RedditClient.
+type RedditSource struct {
+	client    *RedditClient  // Client used to reach the Reddit API
+	subreddit string         // Normalized subreddit name, without the "r/" prefix
+	opts      ListingOptions // Listing sort, window and pagination
+}
+
+// NewRedditSource creates a RedditSource for the given subreddit and listing options.
+// It returns an error if the subreddit name is invalid.
+func NewRedditSource(client *RedditClient, subreddit string, opts ListingOptions) (*RedditSource, error) {
+	normalized, err := config.NormalizeSubreddit(subreddit)
+	if err != nil {
+		return nil, err
+	}
+	return &RedditSource{
+		client:    client,
+		subreddit: normalized,
+		opts:      opts,
+	}, nil
+}
+
+// Name identifies the source as "reddit:<subreddit>".
+func (s *RedditSource) Name() string {
+	return SourceReddit + ":" + s.subreddit
+}
+
+// Fetch retrieves the configured listing of the subreddit.
+func (s *RedditSource) Fetch(ctx context.Context) (*ListingResult, error) {
+	return s.client.FetchTrendingPosts(ctx, s.subreddit, s.opts)
+}
+
+// FetchComments retrieves up to limit top comments of the post with the given ID.
+func (s *RedditSource) FetchComments(ctx context.Context, itemID string, limit int) ([]models.RedditComment, error) {
+	return s.client.FetchPostComments(ctx, itemID, limit)
+}
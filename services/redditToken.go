@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenRefreshLeeway is how long before its reported expiry a cached token is considered stale and refreshed.
+const TokenRefreshLeeway = 60 * time.Second
+
+// DefaultTokenLifetime is the lifetime assumed for tokens whose response reports no positive expires_in.
+// Reddit tokens last an hour.
+const DefaultTokenLifetime = time.Hour
+
+// TokenSource caches a Reddit bearer token and refreshes it shortly before it expires.
+//
+// It is safe for concurrent use. Callers that need a token while a refresh is in flight wait for that
+// refresh instead of starting their own, so a burst of requests results in a single auth request.
+type TokenSource struct {
+	mu      sync.Mutex                            // Guards token, expiry and refresh
+	token   string                                // The cached access token, empty when no valid token is cached
+	expiry  time.Time                             // The time after which the cached token must be refreshed
+	refresh *tokenRefresh                         // The refresh in flight, nil when none is
+	fetch   func(context.Context) (*Token, error) // Function performing the actual auth request
+	now     func() time.Time                      // Clock used to evaluate expiry
+}
+
+// tokenRefresh is a single auth request shared by every caller waiting for a token.
+type tokenRefresh struct {
+	done  chan struct{} // Closed once the refresh finished
+	token string        // The refreshed access token, empty if the refresh failed
+	err   error         // Error of the failed refresh
+}
+
+// NewTokenSource creates a TokenSource that obtains tokens with the given fetch function.
+func NewTokenSource(fetch func(context.Context) (*Token, error)) *TokenSource {
+	return &TokenSource{
+		fetch: fetch,
+		now:   time.Now,
+	}
+}
+
+// Token returns the cached access token, refreshing it first if it is missing or about to expire.
+// The refresh is shared by all concurrent callers and is not cancelled with ctx; a caller whose ctx is done
+// stops waiting and returns ctx's error, while the refresh completes for the others. A failed refresh leaves
+// no token cached, so the next caller retries it.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	// Reuse the cached token while it is still fresh
+	if s.token != "" && s.now().Before(s.expiry) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	refresh := s.refresh
+	if refresh == nil {
+		refresh = &tokenRefresh{done: make(chan struct{})}
+		s.refresh = refresh
+		go s.runRefresh(context.WithoutCancel(ctx), refresh)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+		return refresh.token, refresh.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// runRefresh performs the auth request of refresh, caches the token it returns and wakes up the waiting callers.
+func (s *TokenSource) runRefresh(ctx context.Context, refresh *tokenRefresh) {
+	token, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(refresh.done)
+
+	s.refresh = nil
+	if err != nil {
+		refresh.err = err
+		return
+	}
+
+	// Refresh ahead of the reported expiry so in-flight requests never carry an expired token
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	lifetime = max(lifetime-TokenRefreshLeeway, 0)
+
+	s.token = token.AccessToken
+	s.expiry = s.now().Add(lifetime)
+	refresh.token = s.token
+}
+
+// Invalidate discards the cached token if it is still the given one, forcing the next call to Token to refresh it.
+// Passing the rejected token makes concurrent 401 responses for the same token trigger a single refresh.
+func (s *TokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+		s.expiry = time.Time{}
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenSourceDefaultLifetime(t *testing.T) {
+	var fetches atomic.Int32
+	source := NewTokenSource(func(ctx context.Context) (*Token, error) {
+		fetches.Add(1)
+		return &Token{AccessToken: "token", ExpiresIn: 0}, nil
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	source.now = func() time.Time { return now }
+
+	for _, elapsed := range []time.Duration{0, 30 * time.Minute} {
+		now = now.Add(elapsed)
+		if _, err := source.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("fetched %d tokens, want a token without expires_in to be cached", fetches.Load())
+	}
+}
+
+func TestTokenSourceWaiterGivesUp(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	source := NewTokenSource(func(ctx context.Context) (*Token, error) {
+		fetches.Add(1)
+		<-release
+		return &Token{AccessToken: "token", ExpiresIn: 3600}, nil
+	})
+
+	// A caller whose context is done stops waiting for the slow refresh
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := source.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context's error", err)
+	}
+
+	// The refresh goes on and serves the next caller
+	close(release)
+	token, err := source.Token(context.Background())
+	if err != nil || token != "token" {
+		t.Fatalf("got %q (%v), want the refreshed token", token, err)
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("fetched %d tokens, want the refresh to be shared", fetches.Load())
+	}
+}
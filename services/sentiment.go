@@ -0,0 +1,17 @@
+package services
+
+// Compound score thresholds used by VADER to classify a text as positive or negative.
+const (
+	PositiveThreshold = 0.05  // Compound scores at or above this value are positive
+	NegativeThreshold = -0.05 // Compound scores at or below this value are negative
+)
+
+// SentimentLabel classifies a VADER compound score as "positive", "negative" or "neutral".
+func SentimentLabel(compound float64) string {
+	if compound >= PositiveThreshold {
+		return "positive"
+	} else if compound <= NegativeThreshold {
+		return "negative"
+	}
+	return "neutral"
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+)
+
+// Source is a provider of trending items, such as Reddit or Hacker News.
+//
+// Every source fetches items from its platform and normalizes them into the common item model,
+// models.TrendingPost, so that they can be stored and analyzed by the same pipeline regardless of their origin.
+type Source interface {
+	// Name identifies the configured source instance (e.g., "reddit:golang" or "hackernews:top").
+	// It is used in logs and to tell jobs apart.
+	Name() string
+
+	// Fetch retrieves the current trending items and normalizes them into trending posts.
+	// Items that cannot be normalized are skipped and reported in the result instead of failing the fetch.
+	// Cancelling ctx aborts the requests in flight.
+	Fetch(ctx context.Context) (*ListingResult, error)
+}
+
+// CommentSource is implemented by sources whose items have comment threads that can be ingested.
+type CommentSource interface {
+	Source
+
+	// FetchComments retrieves up to limit comments of the item with the given ID.
+	FetchComments(ctx context.Context, itemID string, limit int) ([]models.RedditComment, error)
+}
+
+// TagSource is implemented by sources that also report trending tags. Tags are not items, so they are fetched
+// and stored apart from the posts.
+type TagSource interface {
+	Source
+
+	// FetchTags retrieves the current trending tags, ranked by their position in the trends.
+	FetchTags(ctx context.Context) ([]models.TrendingTag, error)
+}